@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"flight-itinerary-api/services"
+)
+
+// CacheControl returns the Echo middleware that makes requests sent with
+// "Cache-Control: no-cache" or "no-store" bypass the result cache
+func CacheControl() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			for _, directive := range strings.Split(req.Header.Get(echo.HeaderCacheControl), ",") {
+				switch strings.ToLower(strings.TrimSpace(directive)) {
+				case "no-cache", "no-store":
+					c.SetRequest(req.WithContext(services.WithCacheBypass(req.Context())))
+					return next(c)
+				}
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"flight-itinerary-api/services"
+)
+
+func TestCacheControl(t *testing.T) {
+	e := echo.New()
+
+	var ctx context.Context
+	handler := CacheControl()(func(c echo.Context) error {
+		ctx = c.Request().Context()
+		return c.NoContent(http.StatusOK)
+	})
+
+	tests := []struct {
+		cacheControl string
+		wantBypass   bool
+	}{
+		{"", false},
+		{"max-age=0", false},
+		{"no-cache", true},
+		{"max-age=0, No-Store", true},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tt.cacheControl != "" {
+			req.Header.Set(echo.HeaderCacheControl, tt.cacheControl)
+		}
+		_ = handler(e.NewContext(req, httptest.NewRecorder()))
+		assert.Equal(t, tt.wantBypass, services.CacheBypassFromContext(ctx), "Cache-Control: %q", tt.cacheControl)
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/services"
+)
+
+// ClientIdentity returns the Echo middleware that tags requests with the identity of their
+// client, so contended workers are shared fairly. Only API keys configured in admission or
+// priority identify a client; any other key is ignored in favour of the client's IP address,
+// since a client minting a fresh key per request would otherwise count as many clients.
+func ClientIdentity(admission *config.AdmissionConfig, priority *config.PriorityConfig) echo.MiddlewareFunc {
+	known := make(map[string]bool, len(admission.ClientWeights)+len(priority.APIKeys))
+	for key := range admission.ClientWeights {
+		known[key] = true
+	}
+	for key := range priority.APIKeys {
+		known[key] = true
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			client := req.Header.Get(HeaderAPIKey)
+			if !known[client] {
+				client = c.RealIP()
+			}
+
+			c.SetRequest(req.WithContext(services.WithClient(req.Context(), client)))
+			return next(c)
+		}
+	}
+}
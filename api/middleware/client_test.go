@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/services"
+)
+
+func TestClientIdentity(t *testing.T) {
+	e := echo.New()
+
+	var ctx context.Context
+	handler := ClientIdentity(
+		&config.AdmissionConfig{ClientWeights: map[string]int{"weighted-key": 3}},
+		&config.PriorityConfig{APIKeys: map[string]string{"tier-key": config.PriorityBulk}},
+	)(func(c echo.Context) error {
+		ctx = c.Request().Context()
+		return c.NoContent(http.StatusOK)
+	})
+	identify := func(key string) string {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("X-Real-IP", "1.1.1.1")
+		if key != "" {
+			req.Header.Set(HeaderAPIKey, key)
+		}
+		_ = handler(e.NewContext(req, httptest.NewRecorder()))
+		return services.ClientFromContext(ctx)
+	}
+
+	// Configured API keys identify the client, its IP identifies it otherwise
+	assert.Equal(t, "weighted-key", identify("weighted-key"))
+	assert.Equal(t, "tier-key", identify("tier-key"))
+	assert.Equal(t, "1.1.1.1", identify(""))
+
+	// Fresh keys sent from one address are all queued as that single client
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, "1.1.1.1", identify(fmt.Sprintf("random-key-%d", i)))
+	}
+}
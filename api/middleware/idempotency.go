@@ -0,0 +1,376 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/services"
+)
+
+// Idempotency headers
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// Idempotency limits
+const (
+	maxIdempotencyKeyLength    = 255
+	maxIdempotentResponseBytes = 1 << 20 // Larger responses are not stored, so retries run again
+)
+
+// statusClientClosedRequest is the status of requests abandoned by their client
+const statusClientClosedRequest = 499
+
+// Idempotency errors
+var (
+	ErrInvalidIdempotencyKey = services.NewError(services.KindInvalidInput, "invalid_idempotency_key", "invalid Idempotency-Key: must be at most 255 characters")
+	ErrIdempotencyKeyInUse   = services.NewError(services.KindConflict, "idempotency_key_in_use", "a request with this Idempotency-Key is still being processed")
+	ErrIdempotencyKeyReused  = services.NewError(services.KindUnprocessable, "idempotency_key_reused", "Idempotency-Key was already used with a different request body")
+	ErrIdempotencyKeysFull   = services.NewError(services.KindUnavailable, "idempotency_keys_exhausted", "too many Idempotency-Keys are in use, retry later")
+)
+
+// Idempotency stores the first response to each Idempotency-Key and replays it to the
+// requests retrying that key
+type Idempotency struct {
+	mu             sync.Mutex
+	records        map[idempotencyScope]*idempotencyRecord
+	clientRecords  map[string]int // Number of records of each client
+	storedBytes    int64          // Total size of the response bodies of the records
+	ttl            time.Duration
+	maxBodyBytes   int64
+	maxKeys        int
+	maxClientKeys  int
+	maxStoredBytes int64
+	now            func() time.Time
+}
+
+// idempotencyScope identifies a key; keys of different clients or endpoints never collide
+type idempotencyScope struct {
+	client string
+	method string
+	path   string
+	key    string
+}
+
+// idempotencyRecord is the request first sent with a key and, once done, its response
+type idempotencyRecord struct {
+	fingerprint [sha256.Size]byte
+	expires     time.Time
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+}
+
+// NewIdempotencyMiddleware creates a new idempotency middleware instance
+func NewIdempotencyMiddleware(ctx context.Context, cfg *config.IdempotencyConfig) *Idempotency {
+	i := &Idempotency{
+		records:        make(map[idempotencyScope]*idempotencyRecord),
+		clientRecords:  make(map[string]int),
+		ttl:            cfg.TTL,
+		maxBodyBytes:   cfg.MaxBodyBytes,
+		maxKeys:        cfg.MaxKeys,
+		maxClientKeys:  cfg.MaxKeysPerClient,
+		maxStoredBytes: cfg.MaxStoredBytes,
+		now:            time.Now,
+	}
+
+	// Start cleanup goroutine if idempotency keys are enabled
+	if cfg.TTL > 0 {
+		go i.cleanupLoop(ctx)
+	}
+
+	return i
+}
+
+// Middleware returns the Echo middleware handler. Requests without an Idempotency-Key run as usual.
+func (i *Idempotency) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			key := req.Header.Get(HeaderIdempotencyKey)
+			if i.ttl <= 0 || key == "" {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return ErrInvalidIdempotencyKey
+			}
+
+			if req.ContentLength > i.maxBodyBytes {
+				return echo.ErrStatusRequestEntityTooLarge
+			}
+
+			scope := idempotencyScope{
+				client: services.ClientFromContext(req.Context()),
+				method: req.Method,
+				path:   req.URL.Path,
+				key:    key,
+			}
+			record, err := i.begin(scope)
+			if err != nil {
+				return err
+			}
+
+			// Fingerprint the request as it is read, so that a key reused for another request is
+			// told apart from a retry without holding the body in memory
+			body := &fingerprintReader{
+				body:      req.Body,
+				hash:      newFingerprint(req.URL.RawQuery),
+				remaining: i.maxBodyBytes,
+			}
+			req.Body = body
+
+			if record.done {
+				if err := body.drain(); err != nil {
+					return err
+				}
+				if body.sum() != record.fingerprint {
+					return ErrIdempotencyKeyReused
+				}
+				return replay(c, record)
+			}
+
+			// Release the key if the response cannot be stored, so that a retry runs again
+			stored := false
+			defer func() {
+				if !stored {
+					i.abandon(scope, record)
+				}
+			}()
+
+			res := c.Response()
+			recorder := &responseRecorder{ResponseWriter: res.Writer}
+			res.Writer = recorder
+			err = next(c)
+
+			// Retries are told apart by the whole body, including whatever the handler left unread
+			drained := body.drain() == nil
+			if body.exceeded && !res.Committed {
+				err = echo.ErrStatusRequestEntityTooLarge
+			}
+			if err != nil {
+				c.Error(err)
+			}
+			res.Writer = recorder.ResponseWriter
+
+			if drained && replayable(res.Status) && !recorder.overflowed {
+				stored = i.complete(record, body.sum(), res.Status, res.Header().Clone(), recorder.body.Bytes())
+			}
+			return err
+		}
+	}
+}
+
+// begin returns the record of scope, creating it for the first request sent with the key.
+// The record returned for a retry is a done copy. Retries arriving while the first request
+// is processed, and new keys once the client or the server holds too many, are turned away.
+func (i *Idempotency) begin(scope idempotencyScope) (*idempotencyRecord, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := i.now()
+	if record, found := i.records[scope]; found {
+		if now.Before(record.expires) {
+			if !record.done {
+				return nil, ErrIdempotencyKeyInUse
+			}
+			stored := *record
+			return &stored, nil
+		}
+		i.remove(scope, record)
+	}
+
+	if len(i.records) >= i.maxKeys || i.clientRecords[scope.client] >= i.maxClientKeys {
+		// Make room by forgetting expired keys before turning the request away
+		i.removeExpired(now)
+		if len(i.records) >= i.maxKeys || i.clientRecords[scope.client] >= i.maxClientKeys {
+			return nil, ErrIdempotencyKeysFull
+		}
+	}
+
+	record := &idempotencyRecord{expires: now.Add(i.ttl)}
+	i.records[scope] = record
+	i.clientRecords[scope.client]++
+	return record, nil
+}
+
+// complete stores the response to the request of record, to be replayed until the TTL elapses.
+// It reports false, storing nothing, when the response does not fit in the storage budget.
+func (i *Idempotency) complete(record *idempotencyRecord, fingerprint [sha256.Size]byte, status int, header http.Header, body []byte) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.storedBytes+int64(len(body)) > i.maxStoredBytes {
+		return false
+	}
+	i.storedBytes += int64(len(body))
+
+	record.done = true
+	record.fingerprint = fingerprint
+	record.status, record.header, record.body = status, header, body
+	record.expires = i.now().Add(i.ttl)
+	return true
+}
+
+// abandon forgets the record of scope
+func (i *Idempotency) abandon(scope idempotencyScope, record *idempotencyRecord) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.records[scope] == record {
+		i.remove(scope, record)
+	}
+}
+
+// remove forgets record, the record of scope. The caller holds the lock.
+func (i *Idempotency) remove(scope idempotencyScope, record *idempotencyRecord) {
+	delete(i.records, scope)
+	i.storedBytes -= int64(len(record.body))
+	if i.clientRecords[scope.client]--; i.clientRecords[scope.client] <= 0 {
+		delete(i.clientRecords, scope.client)
+	}
+}
+
+// removeExpired forgets the records whose TTL has elapsed. The caller holds the lock.
+func (i *Idempotency) removeExpired(now time.Time) {
+	for scope, record := range i.records {
+		if !now.Before(record.expires) {
+			i.remove(scope, record)
+		}
+	}
+}
+
+// newFingerprint returns the hash fingerprinting a request, fed with its query. The body is
+// written to it next.
+func newFingerprint(query string) hash.Hash {
+	h := sha256.New()
+	h.Write([]byte(query))
+	h.Write([]byte{0})
+	return h
+}
+
+// replayable reports whether a response of the given status is stored for replay. Transient
+// failures are not, so that retries get another chance.
+func replayable(status int) bool {
+	return status < http.StatusInternalServerError &&
+		status != http.StatusTooManyRequests &&
+		status != statusClientClosedRequest
+}
+
+// replay writes the stored response of record
+func replay(c echo.Context, record *idempotencyRecord) error {
+	res := c.Response()
+	header := res.Header()
+	for name, values := range record.header {
+		header[name] = values
+	}
+	header.Set(HeaderIdempotentReplayed, "true")
+
+	res.WriteHeader(record.status)
+	_, err := res.Write(record.body)
+	return err
+}
+
+// cleanup removes records whose TTL has elapsed
+func (i *Idempotency) cleanup() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.removeExpired(i.now())
+}
+
+// cleanupLoop runs the cleanup process periodically until ctx is done
+func (i *Idempotency) cleanupLoop(ctx context.Context) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			i.cleanup()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// responseRecorder copies the body written to a response, up to maxIdempotentResponseBytes
+type responseRecorder struct {
+	http.ResponseWriter
+	body       bytes.Buffer
+	overflowed bool
+}
+
+// Write writes b to the response, recording it
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if !r.overflowed {
+		if r.body.Len()+len(b) > maxIdempotentResponseBytes {
+			r.overflowed = true
+			r.body = bytes.Buffer{}
+		} else {
+			r.body.Write(b)
+		}
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying response writer, so that flushing still reaches it
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// fingerprintReader hashes a request body as it is read, failing once the body grows past the
+// bytes remaining
+type fingerprintReader struct {
+	body      io.ReadCloser
+	hash      hash.Hash
+	remaining int64
+	exceeded  bool
+}
+
+// Read reads from the body, hashing what it returns
+func (r *fingerprintReader) Read(p []byte) (int, error) {
+	if r.exceeded {
+		return 0, echo.ErrStatusRequestEntityTooLarge
+	}
+
+	// Read one byte past the limit to tell a body of exactly the limit from a larger one
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.body.Read(p)
+	if int64(n) > r.remaining {
+		n, r.exceeded, err = int(r.remaining), true, echo.ErrStatusRequestEntityTooLarge
+	}
+	r.remaining -= int64(n)
+	r.hash.Write(p[:n])
+	return n, err
+}
+
+// Close closes the body
+func (r *fingerprintReader) Close() error {
+	return r.body.Close()
+}
+
+// drain reads the rest of the body
+func (r *fingerprintReader) drain() error {
+	_, err := io.Copy(io.Discard, r)
+	return err
+}
+
+// sum returns the fingerprint of the request once its body has been read
+func (r *fingerprintReader) sum() [sha256.Size]byte {
+	var sum [sha256.Size]byte
+	r.hash.Sum(sum[:0])
+	return sum
+}
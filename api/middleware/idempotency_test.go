@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/services"
+)
+
+func TestIdempotency(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	idempotency := NewIdempotencyMiddleware(ctx, testIdempotencyConfig())
+	now := time.Now()
+	idempotency.now = func() time.Time { return now }
+
+	// The handler fails transiently while failing is set, and otherwise reports how many
+	// times it ran along with the body it received
+	e := echo.New()
+	calls, failing := 0, false
+	handler := idempotency.Middleware()(func(c echo.Context) error {
+		calls++
+		if failing {
+			return services.ErrOverloaded
+		}
+		body, _ := io.ReadAll(c.Request().Body)
+		return c.String(http.StatusCreated, fmt.Sprintf("%d %s", calls, body))
+	})
+
+	sendTo := func(target, client, key, body string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req = req.WithContext(services.WithClient(req.Context(), client))
+		if key != "" {
+			req.Header.Set(HeaderIdempotencyKey, key)
+		}
+		rec := httptest.NewRecorder()
+		return rec, handler(e.NewContext(req, rec))
+	}
+	send := func(client, key, body string) (*httptest.ResponseRecorder, error) {
+		return sendTo("/api/itinerary", client, key, body)
+	}
+
+	rec, err := send("alice", "k1", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "1 a", rec.Body.String())
+	assert.Empty(t, rec.Header().Get(HeaderIdempotentReplayed))
+
+	// A retry replays the first response without running the handler
+	rec, err = send("alice", "k1", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "1 a", rec.Body.String())
+	assert.Equal(t, "true", rec.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(t, echo.MIMETextPlainCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+
+	// Reusing the key for another body or query is rejected
+	_, err = send("alice", "k1", "b")
+	assert.Equal(t, ErrIdempotencyKeyReused, err)
+	_, err = sendTo("/api/itinerary?store=true", "alice", "k1", "a")
+	assert.Equal(t, ErrIdempotencyKeyReused, err)
+
+	// Requests without a key, and keys of other clients, are independent
+	rec, _ = send("alice", "", "a")
+	assert.Equal(t, "2 a", rec.Body.String())
+	rec, _ = send("bob", "k1", "b")
+	assert.Equal(t, "3 b", rec.Body.String())
+
+	// Transient failures are not stored, so a retry runs again
+	failing = true
+	_, err = send("alice", "k2", "a")
+	assert.Equal(t, services.ErrOverloaded, err)
+	failing = false
+	rec, _ = send("alice", "k2", "a")
+	assert.Equal(t, "5 a", rec.Body.String())
+
+	// Keys expire after the TTL
+	now = now.Add(time.Hour)
+	rec, _ = send("alice", "k1", "b")
+	assert.Equal(t, "6 b", rec.Body.String())
+
+	_, err = send("alice", strings.Repeat("k", maxIdempotencyKeyLength+1), "a")
+	assert.Equal(t, ErrInvalidIdempotencyKey, err)
+
+	idempotency.cleanup()
+	assert.Equal(t, 1, len(idempotency.records))
+	now = now.Add(time.Hour)
+	idempotency.cleanup()
+	assert.Empty(t, idempotency.records)
+}
+
+func TestIdempotencyKeyInUse(t *testing.T) {
+	cfg := testIdempotencyConfig()
+	cfg.TTL = 0
+	idempotency := NewIdempotencyMiddleware(context.Background(), cfg)
+	idempotency.ttl = time.Hour
+
+	scope := idempotencyScope{client: "alice", method: http.MethodPost, path: "/api/jobs", key: "k"}
+	record, err := idempotency.begin(scope)
+	assert.NoError(t, err)
+
+	// A retry arriving while the first request is processed is turned away
+	_, err = idempotency.begin(scope)
+	assert.Equal(t, ErrIdempotencyKeyInUse, err)
+
+	// Once the first request is abandoned, the retry runs
+	idempotency.abandon(scope, record)
+	_, err = idempotency.begin(scope)
+	assert.NoError(t, err)
+}
+
+func TestIdempotencyLimits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := testIdempotencyConfig()
+	cfg.MaxBodyBytes = 8
+	cfg.MaxKeys = 3
+	cfg.MaxKeysPerClient = 2
+	cfg.MaxStoredBytes = 10
+	idempotency := NewIdempotencyMiddleware(ctx, cfg)
+	now := time.Now()
+	idempotency.now = func() time.Time { return now }
+
+	// The handler reads at most four bytes of the body and responds with what it read
+	e := echo.New()
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		_ = c.NoContent(err.(*echo.HTTPError).Code)
+	}
+	handler := idempotency.Middleware()(func(c echo.Context) error {
+		body, err := io.ReadAll(io.LimitReader(c.Request().Body, 4))
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusCreated, string(body))
+	})
+	send := func(client, key, body string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/api/itinerary", strings.NewReader(body))
+		req = req.WithContext(services.WithClient(req.Context(), client))
+		req.Header.Set(HeaderIdempotencyKey, key)
+		rec := httptest.NewRecorder()
+		return rec, handler(e.NewContext(req, rec))
+	}
+
+	// Bodies past the limit are refused, and never stored when their length is not announced
+	_, err := send("alice", "big", "abcdefghi")
+	assert.Equal(t, echo.ErrStatusRequestEntityTooLarge, err)
+	req := httptest.NewRequest(http.MethodPost, "/api/itinerary", strings.NewReader("abcdefghi"))
+	req.ContentLength = -1
+	req.Header.Set(HeaderIdempotencyKey, "chunked")
+	_ = handler(e.NewContext(req, httptest.NewRecorder()))
+	assert.Empty(t, idempotency.records)
+
+	// The whole body is fingerprinted, including what the handler did not read
+	rec, err := send("alice", "k1", "abcdefgh")
+	assert.NoError(t, err)
+	assert.Equal(t, "abcd", rec.Body.String())
+	_, err = send("alice", "k1", "abcdxxxx")
+	assert.Equal(t, ErrIdempotencyKeyReused, err)
+	_, err = send("alice", "k1", "abcdefghi")
+	assert.Equal(t, echo.ErrStatusRequestEntityTooLarge, err)
+	rec, _ = send("alice", "k1", "abcdefgh")
+	assert.Equal(t, "true", rec.Header().Get(HeaderIdempotentReplayed))
+
+	// Each client, and the server as a whole, hold a bounded number of keys
+	_, err = send("alice", "k2", "ab")
+	assert.NoError(t, err)
+	_, err = send("alice", "k3", "ab")
+	assert.Equal(t, ErrIdempotencyKeysFull, err)
+	_, err = send("bob", "k1", "ab")
+	assert.NoError(t, err)
+	_, err = send("carol", "k1", "ab")
+	assert.Equal(t, ErrIdempotencyKeysFull, err)
+
+	// Expired keys make room again
+	now = now.Add(time.Hour)
+	_, err = send("carol", "k1", "abcd")
+	assert.NoError(t, err)
+
+	// Responses past the storage budget are not stored, so retries run again
+	_, err = send("carol", "k2", "abcd")
+	assert.NoError(t, err)
+	_, err = send("dave", "k1", "abcd")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(idempotency.records))
+	assert.Equal(t, int64(8), idempotency.storedBytes)
+}
+
+// testIdempotencyConfig returns a configuration whose limits are out of the way of the tests
+func testIdempotencyConfig() *config.IdempotencyConfig {
+	return &config.IdempotencyConfig{
+		TTL:              time.Hour,
+		MaxBodyBytes:     1 << 20,
+		MaxKeys:          100,
+		MaxKeysPerClient: 100,
+		MaxStoredBytes:   1 << 20,
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/services"
+)
+
+// Request headers carrying priority metadata
+const (
+	HeaderAPIKey   = "X-API-Key"
+	HeaderPriority = "X-Priority"
+)
+
+// PriorityClassifier assigns a scheduling priority to requests from their metadata
+type PriorityClassifier struct {
+	apiKeys map[string]services.Priority
+}
+
+// NewPriorityMiddleware creates a new priority classifier from the API key tiers in cfg
+func NewPriorityMiddleware(cfg *config.PriorityConfig) *PriorityClassifier {
+	apiKeys := make(map[string]services.Priority, len(cfg.APIKeys))
+	for key, class := range cfg.APIKeys {
+		if p, ok := services.ParsePriority(class); ok {
+			apiKeys[key] = p
+		}
+	}
+
+	return &PriorityClassifier{
+		apiKeys: apiKeys,
+	}
+}
+
+// Middleware returns the Echo middleware handler scheduling requests with fallback priority,
+// unless their API key belongs to a configured tier. Clients may lower, but never raise,
+// the priority of their own requests through the X-Priority header.
+func (p *PriorityClassifier) Middleware(fallback services.Priority) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			priority := fallback
+			if tier, ok := p.apiKeys[req.Header.Get(HeaderAPIKey)]; ok {
+				priority = tier
+			}
+			if requested, ok := services.ParsePriority(req.Header.Get(HeaderPriority)); ok && requested > priority {
+				priority = requested
+			}
+
+			c.SetRequest(req.WithContext(services.WithPriority(req.Context(), priority)))
+			return next(c)
+		}
+	}
+}
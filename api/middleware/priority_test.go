@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/services"
+)
+
+func TestPriorityMiddleware(t *testing.T) {
+	cfg := &config.PriorityConfig{
+		APIKeys: map[string]string{
+			"live-agent": config.PriorityInteractive,
+			"nightly":    config.PriorityBulk,
+		},
+	}
+	classifier := NewPriorityMiddleware(cfg)
+
+	tests := []struct {
+		name     string
+		fallback services.Priority
+		apiKey   string
+		header   string
+		want     services.Priority
+	}{
+		{"route default", services.PriorityInteractive, "", "", services.PriorityInteractive},
+		{"bulk route default", services.PriorityBulk, "", "", services.PriorityBulk},
+		{"bulk API key", services.PriorityInteractive, "nightly", "", services.PriorityBulk},
+		{"interactive API key", services.PriorityBulk, "live-agent", "", services.PriorityInteractive},
+		{"unknown API key", services.PriorityBulk, "unknown", "", services.PriorityBulk},
+		{"header lowers priority", services.PriorityInteractive, "", "bulk", services.PriorityBulk},
+		{"header cannot raise priority", services.PriorityBulk, "", "interactive", services.PriorityBulk},
+		{"invalid header", services.PriorityInteractive, "", "urgent", services.PriorityInteractive},
+	}
+
+	e := echo.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got services.Priority
+			handler := classifier.Middleware(tt.fallback)(func(c echo.Context) error {
+				got = services.PriorityFromContext(c.Request().Context())
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.apiKey != "" {
+				req.Header.Set(HeaderAPIKey, tt.apiKey)
+			}
+			if tt.header != "" {
+				req.Header.Set(HeaderPriority, tt.header)
+			}
+			_ = handler(e.NewContext(req, httptest.NewRecorder()))
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
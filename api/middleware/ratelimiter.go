@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -28,6 +29,12 @@ type rateLimiterEntry struct {
 	lastAccess time.Time
 }
 
+// rateLimiterContextKey is the echo context key under which the limiter of the current client is stored
+const rateLimiterContextKey = "rateLimiter"
+
+// ErrTooManyRequests is returned when a client has exhausted its rate limit
+var ErrTooManyRequests = echo.NewHTTPError(http.StatusTooManyRequests, "Too many requests")
+
 // cleanup duration constants
 const (
 	cleanupInterval = 5 * time.Minute // How often cleanup runs
@@ -80,16 +87,65 @@ func (i *IPRateLimiter) Middleware() echo.MiddlewareFunc {
 				return next(c)
 			}
 			ip := c.RealIP()
-			if !i.GetLimiter(ip).Allow() {
-				return c.JSON(http.StatusTooManyRequests, map[string]string{
-					"error": "Too many requests",
-				})
+			limiter := i.GetLimiter(ip)
+			if !limiter.Allow() {
+				return tooManyRequests(c, limiter, 1)
 			}
+			// Expose the limiter so handlers can charge for requests carrying several units of work
+			c.Set(rateLimiterContextKey, limiter)
 			return next(c)
 		}
 	}
 }
 
+// ConsumeRateLimit charges n additional tokens to the client of the current request.
+// It returns ErrTooManyRequests, with a Retry-After header, when the tokens are not available;
+// requests charging more tokens than RateLimitBurst allows are never granted them.
+func ConsumeRateLimit(c echo.Context, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	limiter, ok := c.Get(rateLimiterContextKey).(*rate.Limiter)
+	if !ok || limiter.AllowN(time.Now(), n) {
+		return nil
+	}
+	return tooManyRequests(c, limiter, n)
+}
+
+// RateLimitCharger returns a function charging n additional tokens to the client of the
+// current request, reporting whether they were available. Unlike ConsumeRateLimit it never
+// touches the response, so it may be called from other goroutines once the response is sent.
+func RateLimitCharger(c echo.Context) func(n int) bool {
+	limiter, ok := c.Get(rateLimiterContextKey).(*rate.Limiter)
+	return func(n int) bool {
+		return !ok || n <= 0 || limiter.AllowN(time.Now(), n)
+	}
+}
+
+// RateLimitBurst returns the most tokens the client of the current request may spend at once,
+// counting the token of the request itself, or 0 when rate limiting is disabled
+func RateLimitBurst(c echo.Context) int {
+	limiter, ok := c.Get(rateLimiterContextKey).(*rate.Limiter)
+	if !ok {
+		return 0
+	}
+	return limiter.Burst()
+}
+
+// tooManyRequests sets the Retry-After header to when limiter can grant n tokens, rounded up
+// to whole seconds, and returns ErrTooManyRequests
+func tooManyRequests(c echo.Context, limiter *rate.Limiter, n int) error {
+	now := time.Now()
+	reservation := limiter.ReserveN(now, n)
+	if reservation.OK() {
+		delay := reservation.DelayFrom(now)
+		reservation.CancelAt(now)
+		seconds := max(int64((delay+time.Second-1)/time.Second), 1)
+		c.Response().Header().Set(echo.HeaderRetryAfter, strconv.FormatInt(seconds, 10))
+	}
+	return ErrTooManyRequests
+}
+
 // cleanup removes entries that haven't been accessed for longer than maxIdleTime
 func (i *IPRateLimiter) cleanup() {
 	i.mu.Lock()
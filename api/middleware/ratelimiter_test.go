@@ -94,3 +94,41 @@ func TestRateLimiterStop(t *testing.T) {
 		t.Error("done channel should be closed")
 	}
 }
+
+func TestConsumeRateLimit(t *testing.T) {
+	cfg := &config.RateLimiterConfig{
+		Enabled:       true,
+		MaxReqsPerMin: 5,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rl := NewRateLimiterMiddleware(ctx, cfg)
+
+	e := echo.New()
+	var charged []error
+	handler := rl.Middleware()(func(c echo.Context) error {
+		assert.Equal(t, 5, RateLimitBurst(c))
+		charged = append(charged, ConsumeRateLimit(c, 3))
+		return c.String(http.StatusOK, "success")
+	})
+
+	// First request: 1 token from the middleware plus 3 charged by the handler
+	// Second request: 1 token left in the bucket, so the extra charge fails
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("X-Real-IP", "3.3.3.3")
+		rec = httptest.NewRecorder()
+		_ = handler(e.NewContext(req, rec))
+	}
+	assert.Equal(t, []error{nil, ErrTooManyRequests}, charged)
+
+	// The three missing tokens are refilled at one every 12 seconds
+	assert.Equal(t, "36", rec.Header().Get(echo.HeaderRetryAfter))
+
+	// Without the rate limiter middleware every charge is allowed
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+	assert.NoError(t, ConsumeRateLimit(c, 100))
+	assert.Equal(t, 0, RateLimitBurst(c))
+}
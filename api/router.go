@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/labstack/echo/v4"
 	echomiddleware "github.com/labstack/echo/v4/middleware"
@@ -17,31 +18,70 @@ import (
 type Router struct {
 	logger           *zap.Logger
 	rateLimiter      *middleware.IPRateLimiter
+	priority         *middleware.PriorityClassifier
+	clientIdentity   echo.MiddlewareFunc
+	idempotency      *middleware.Idempotency
+	metrics          http.Handler
 	itineraryHandler *handlers.ItineraryHandler
+	jobHandler       *handlers.JobHandler
 }
 
 // NewRouter creates a new instance of Router
-func NewRouter(ctx context.Context, cfg *config.AppConfig, logger *zap.Logger, itineraryService *services.ItineraryService) *Router {
+func NewRouter(ctx context.Context, cfg *config.AppConfig, logger *zap.Logger, itineraryService *services.ItineraryService, itineraryStore *services.ItineraryStore, jobManager *services.JobManager) *Router {
 	// Create rate limiter
 	rateLimiter := middleware.NewRateLimiterMiddleware(ctx, &cfg.RateLimiter)
 
 	return &Router{
 		logger:           logger,
 		rateLimiter:      rateLimiter,
-		itineraryHandler: handlers.NewItineraryHandler(itineraryService),
+		priority:         middleware.NewPriorityMiddleware(&cfg.Priority),
+		clientIdentity:   middleware.ClientIdentity(&cfg.Admission, &cfg.Priority),
+		idempotency:      middleware.NewIdempotencyMiddleware(ctx, &cfg.Idempotency),
+		metrics:          itineraryService.Metrics(),
+		itineraryHandler: handlers.NewItineraryHandler(itineraryService, itineraryStore),
+		jobHandler:       handlers.NewJobHandler(jobManager),
 	}
 }
 
 // SetupRoutes configures all the routes for the application
 func (r *Router) SetupRoutes(e *echo.Echo) {
+	// Every error is reported as application/problem+json
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+
 	// Middleware
 	e.Use(middleware.Logger(r.logger))
 	e.Use(echomiddleware.Recover())
 	e.Use(echomiddleware.CORS())
 
-	// API group with rate limiting
-	api := e.Group("/api", r.rateLimiter.Middleware())
+	// Metrics in the Prometheus text format
+	e.GET("/metrics", echo.WrapHandler(r.metrics))
+
+	// API group with rate limiting, fair sharing of workers between clients and result cache control
+	api := e.Group("/api", r.rateLimiter.Middleware(), r.clientIdentity, middleware.CacheControl())
+
+	// Itinerary routes; single itineraries are interactive, multi-itinerary requests are bulk work
+	interactive := r.priority.Middleware(services.PriorityInteractive)
+	bulk := r.priority.Middleware(services.PriorityBulk)
+
+	// Writes honour Idempotency-Key; streams are left out since replaying them would buffer them whole
+	idempotent := r.idempotency.Middleware()
+	api.POST("/itinerary", r.itineraryHandler.ProcessItinerary, idempotent, interactive)
+	api.POST("/itineraries/batch", r.itineraryHandler.ProcessBatch, idempotent, bulk)
+	api.POST("/itineraries/stream", r.itineraryHandler.ProcessStream, bulk)
+	api.POST("/itineraries/diff", r.itineraryHandler.DiffItineraries, interactive)
+
+	// Stored itinerary routes
+	api.GET("/itineraries", r.itineraryHandler.ListItineraries)
+	api.GET("/itineraries/:id", r.itineraryHandler.GetItinerary).Name = handlers.ItineraryRouteName
+	api.PATCH("/itineraries/:id", r.itineraryHandler.PatchItinerary, idempotent)
+	api.GET("/itineraries/:id/revisions", r.itineraryHandler.GetRevisions)
+	api.GET("/itineraries/:id/revisions/:revision", r.itineraryHandler.GetRevision)
+	api.GET("/itineraries/:id/diff", r.itineraryHandler.DiffRevisions)
 
-	// Itinerary routes
-	api.POST("/itinerary", r.itineraryHandler.ProcessItinerary)
+	// Asynchronous job routes
+	api.POST("/jobs", r.jobHandler.CreateJob, idempotent)
+	api.GET("/jobs/:id", r.jobHandler.GetJob)
+	api.DELETE("/jobs/:id", r.jobHandler.CancelJob)
+	api.GET("/jobs/:id/deliveries", r.jobHandler.GetDeliveries)
+	api.GET("/jobs/:id/events", r.jobHandler.StreamJobEvents)
 }
@@ -3,14 +3,29 @@ package config
 import (
 	"fmt"
 	"os"
+	"runtime"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // AppConfig holds all application configurations
 type AppConfig struct {
-	Server      ServerConfig
-	RateLimiter RateLimiterConfig
-	WorkerPool  WorkerPoolConfig
+	Server         ServerConfig
+	RateLimiter    RateLimiterConfig
+	WorkerPool     WorkerPoolConfig
+	LargePool      LargePoolConfig
+	Admission      AdmissionConfig
+	Priority       PriorityConfig
+	Batch          BatchConfig
+	Stream         StreamConfig
+	Jobs           JobsConfig
+	Webhook        WebhookConfig
+	Reconstruction ReconstructionConfig
+	Cache          CacheConfig
+	Coalescing     CoalescingConfig
+	Idempotency    IdempotencyConfig
+	Storage        StorageConfig
 }
 
 // ServerConfig holds HTTP server related configurations
@@ -24,9 +39,128 @@ type RateLimiterConfig struct {
 	MaxReqsPerMin int
 }
 
+// Executors running reconstruction tasks
+const (
+	ExecutorAnts      = "ants"
+	ExecutorSemaphore = "semaphore"
+	ExecutorInline    = "inline"
+)
+
 // WorkerPoolConfig holds worker pool related configurations
 type WorkerPoolConfig struct {
 	WorkerCount int
+	Executor    string
+}
+
+// LargePoolConfig holds the configuration of the dedicated pool running large requests.
+// Requests of at least Threshold tickets use it; a zero Threshold disables the pool.
+type LargePoolConfig struct {
+	Threshold     int
+	WorkerCount   int
+	MaxQueueDepth int
+	MaxQueueWait  time.Duration
+}
+
+// AdmissionConfig holds worker pool admission control related configurations.
+// Zero values disable the corresponding limit.
+type AdmissionConfig struct {
+	MaxQueueDepth int
+	MaxQueueWait  time.Duration
+	NonBlocking   bool
+	TargetLatency time.Duration
+	RetryAfter    time.Duration
+
+	// ClientWeights maps API keys or client IPs to their share of contended workers;
+	// unlisted clients have a weight of 1
+	ClientWeights map[string]int
+}
+
+// Priority classes of reconstructions
+const (
+	PriorityInteractive = "interactive"
+	PriorityBulk        = "bulk"
+)
+
+// PriorityConfig holds request priority related configurations
+type PriorityConfig struct {
+	// APIKeys maps API keys to the priority class of their requests
+	APIKeys map[string]string
+}
+
+// BatchConfig holds batch processing related configurations
+type BatchConfig struct {
+	MaxItems int
+}
+
+// StreamConfig holds NDJSON streaming related configurations
+type StreamConfig struct {
+	MaxInFlight  int
+	MaxLineBytes int
+}
+
+// JobsConfig holds asynchronous job related configurations
+type JobsConfig struct {
+	QueueSize       int
+	Workers         int
+	MaxItems        int
+	ResultRetention time.Duration
+}
+
+// WebhookConfig holds job completion callback related configurations
+type WebhookConfig struct {
+	Secret         string
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration
+	// AllowPrivateNetworks lets callbacks reach loopback, private and link-local addresses,
+	// which are refused by default so that clients cannot probe the internal network
+	AllowPrivateNetworks bool
+}
+
+// ReconstructionConfig holds itinerary reconstruction algorithm related configurations
+type ReconstructionConfig struct {
+	ParallelThreshold int
+	ParallelWorkers   int
+	ProcessingTimeout time.Duration
+}
+
+// CacheConfig holds reconstruction result cache related configurations. A zero MaxEntries
+// disables the cache, and ticket sets of more than MaxTickets tickets are never cached.
+type CacheConfig struct {
+	MaxEntries int
+	MaxTickets int
+	TTL        time.Duration
+}
+
+// CoalescingConfig holds request coalescing related configurations. Identical ticket sets of
+// at most MaxTickets tickets in flight at the same time are reconstructed once; a zero
+// MaxTickets disables coalescing.
+type CoalescingConfig struct {
+	MaxTickets int
+}
+
+// IdempotencyConfig holds Idempotency-Key related configurations. The first response to a key
+// is replayed to requests retrying it for TTL; a zero TTL disables idempotency keys.
+type IdempotencyConfig struct {
+	TTL              time.Duration
+	MaxBodyBytes     int64 // Largest request body sent with a key
+	MaxKeys          int   // Keys remembered at once, across clients
+	MaxKeysPerClient int   // Keys remembered at once for a single client
+	MaxStoredBytes   int64 // Total size of the responses kept for replay
+}
+
+// Backends storing itineraries
+const (
+	StorageMemory = "memory"
+	StorageFile   = "file"
+)
+
+// StorageConfig holds stored itinerary related configurations. Path is the data file of the
+// file backend.
+type StorageConfig struct {
+	Backend string
+	Path    string
 }
 
 // LoadConfig loads application configurations from environment variables
@@ -37,6 +171,29 @@ func LoadConfig() (*AppConfig, error) {
 		},
 		RateLimiter: RateLimiterConfig{},
 		WorkerPool:  WorkerPoolConfig{},
+		LargePool:   LargePoolConfig{},
+		Admission: AdmissionConfig{
+			ClientWeights: make(map[string]int),
+		},
+		Priority: PriorityConfig{
+			APIKeys: make(map[string]string),
+		},
+		Batch:  BatchConfig{},
+		Stream: StreamConfig{},
+		Jobs:   JobsConfig{},
+		Webhook: WebhookConfig{
+			Secret: os.Getenv("WEBHOOK_SECRET"),
+		},
+		Reconstruction: ReconstructionConfig{
+			ParallelWorkers: runtime.GOMAXPROCS(0),
+		},
+		Cache:       CacheConfig{},
+		Coalescing:  CoalescingConfig{},
+		Idempotency: IdempotencyConfig{},
+		Storage: StorageConfig{
+			Backend: getEnvWithDefault("STORAGE_BACKEND", StorageMemory),
+			Path:    getEnvWithDefault("STORAGE_PATH", "itineraries.jsonl"),
+		},
 	}
 
 	// Configure worker pool
@@ -45,6 +202,81 @@ func LoadConfig() (*AppConfig, error) {
 		config.WorkerPool.WorkerCount = parsed
 	}
 
+	config.WorkerPool.Executor = getEnvWithDefault("EXECUTOR", ExecutorAnts)
+
+	// Configure the pool dedicated to large requests
+	largeThreshold := getEnvWithDefault("LARGE_REQUEST_THRESHOLD", "10000")
+	if parsed, err := strconv.Atoi(largeThreshold); err == nil && parsed >= 0 {
+		config.LargePool.Threshold = parsed
+	}
+
+	largeWorkerCount := getEnvWithDefault("LARGE_WORKER_COUNT", "4")
+	if parsed, err := strconv.Atoi(largeWorkerCount); err == nil && parsed > 0 {
+		config.LargePool.WorkerCount = parsed
+	}
+
+	largeMaxQueueDepth := getEnvWithDefault("LARGE_MAX_QUEUE", "100")
+	if parsed, err := strconv.Atoi(largeMaxQueueDepth); err == nil && parsed >= 0 {
+		config.LargePool.MaxQueueDepth = parsed
+	}
+
+	largeMaxQueueWait := getEnvWithDefault("LARGE_MAX_WAIT", "10s")
+	if parsed, err := time.ParseDuration(largeMaxQueueWait); err == nil && parsed >= 0 {
+		config.LargePool.MaxQueueWait = parsed
+	}
+
+	// Configure admission control in front of the worker pool
+	maxQueueDepth := getEnvWithDefault("ADMISSION_MAX_QUEUE", "1000")
+	if parsed, err := strconv.Atoi(maxQueueDepth); err == nil && parsed >= 0 {
+		config.Admission.MaxQueueDepth = parsed
+	}
+
+	maxQueueWait := getEnvWithDefault("ADMISSION_MAX_WAIT", "1s")
+	if parsed, err := time.ParseDuration(maxQueueWait); err == nil && parsed >= 0 {
+		config.Admission.MaxQueueWait = parsed
+	}
+
+	config.Admission.NonBlocking = getEnvWithDefault("ADMISSION_NONBLOCKING", "disabled") == "enabled"
+
+	targetLatency := getEnvWithDefault("ADMISSION_TARGET_LATENCY", "250ms")
+	if parsed, err := time.ParseDuration(targetLatency); err == nil && parsed >= 0 {
+		config.Admission.TargetLatency = parsed
+	}
+
+	retryAfter := getEnvWithDefault("ADMISSION_RETRY_AFTER", "1s")
+	if parsed, err := time.ParseDuration(retryAfter); err == nil && parsed > 0 {
+		config.Admission.RetryAfter = parsed
+	}
+
+	// Configure client weights as comma-separated client:weight pairs
+	if clientWeights := os.Getenv("CLIENT_WEIGHTS"); clientWeights != "" {
+		for _, pair := range strings.Split(clientWeights, ",") {
+			// Split on the last colon, as IPv6 client addresses contain colons themselves
+			pair = strings.TrimSpace(pair)
+			sep := strings.LastIndex(pair, ":")
+			if sep <= 0 {
+				return nil, fmt.Errorf("invalid client weight entry %q", pair)
+			}
+			client, weight := pair[:sep], pair[sep+1:]
+			parsed, err := strconv.Atoi(weight)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("invalid client weight entry %q", pair)
+			}
+			config.Admission.ClientWeights[client] = parsed
+		}
+	}
+
+	// Configure API key priority tiers as comma-separated key:class pairs
+	if apiKeys := os.Getenv("PRIORITY_API_KEYS"); apiKeys != "" {
+		for _, pair := range strings.Split(apiKeys, ",") {
+			key, class, found := strings.Cut(strings.TrimSpace(pair), ":")
+			if !found || key == "" {
+				return nil, fmt.Errorf("invalid priority API key entry %q", pair)
+			}
+			config.Priority.APIKeys[key] = class
+		}
+	}
+
 	rateLimitStatus := getEnvWithDefault("RATE_LIMITER", "disabled")
 	if rateLimitStatus == "enabled" {
 		config.RateLimiter.Enabled = true
@@ -57,6 +289,133 @@ func LoadConfig() (*AppConfig, error) {
 		config.RateLimiter.MaxReqsPerMin = parsed
 	}
 
+	maxBatchItems := getEnvWithDefault("MAX_BATCH_SIZE", "1000")
+	if parsed, err := strconv.Atoi(maxBatchItems); err == nil && parsed > 0 {
+		config.Batch.MaxItems = parsed
+	}
+
+	maxInFlight := getEnvWithDefault("STREAM_MAX_IN_FLIGHT", "64")
+	if parsed, err := strconv.Atoi(maxInFlight); err == nil && parsed > 0 {
+		config.Stream.MaxInFlight = parsed
+	}
+
+	maxLineBytes := getEnvWithDefault("STREAM_MAX_LINE_BYTES", "1048576")
+	if parsed, err := strconv.Atoi(maxLineBytes); err == nil && parsed > 0 {
+		config.Stream.MaxLineBytes = parsed
+	}
+
+	// Configure asynchronous jobs
+	jobQueueSize := getEnvWithDefault("JOB_QUEUE_SIZE", "100")
+	if parsed, err := strconv.Atoi(jobQueueSize); err == nil && parsed > 0 {
+		config.Jobs.QueueSize = parsed
+	}
+
+	jobWorkers := getEnvWithDefault("JOB_WORKERS", "4")
+	if parsed, err := strconv.Atoi(jobWorkers); err == nil && parsed > 0 {
+		config.Jobs.Workers = parsed
+	}
+
+	jobMaxItems := getEnvWithDefault("JOB_MAX_ITEMS", "100000")
+	if parsed, err := strconv.Atoi(jobMaxItems); err == nil && parsed > 0 {
+		config.Jobs.MaxItems = parsed
+	}
+
+	jobRetention := getEnvWithDefault("JOB_RESULT_RETENTION", "1h")
+	if parsed, err := time.ParseDuration(jobRetention); err == nil && parsed > 0 {
+		config.Jobs.ResultRetention = parsed
+	}
+
+	// Configure job completion callbacks
+	webhookAttempts := getEnvWithDefault("WEBHOOK_MAX_ATTEMPTS", "5")
+	if parsed, err := strconv.Atoi(webhookAttempts); err == nil && parsed > 0 {
+		config.Webhook.MaxAttempts = parsed
+	}
+
+	webhookBackoff := getEnvWithDefault("WEBHOOK_INITIAL_BACKOFF", "1s")
+	if parsed, err := time.ParseDuration(webhookBackoff); err == nil && parsed > 0 {
+		config.Webhook.InitialBackoff = parsed
+	}
+
+	webhookMaxBackoff := getEnvWithDefault("WEBHOOK_MAX_BACKOFF", "1m")
+	if parsed, err := time.ParseDuration(webhookMaxBackoff); err == nil && parsed > 0 {
+		config.Webhook.MaxBackoff = parsed
+	}
+
+	webhookTimeout := getEnvWithDefault("WEBHOOK_TIMEOUT", "10s")
+	if parsed, err := time.ParseDuration(webhookTimeout); err == nil && parsed > 0 {
+		config.Webhook.Timeout = parsed
+	}
+
+	webhookAllowPrivate := getEnvWithDefault("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "false")
+	if parsed, err := strconv.ParseBool(webhookAllowPrivate); err == nil {
+		config.Webhook.AllowPrivateNetworks = parsed
+	}
+
+	// Configure the reconstruction algorithm
+	parallelThreshold := getEnvWithDefault("PARALLEL_THRESHOLD", "100000")
+	if parsed, err := strconv.Atoi(parallelThreshold); err == nil && parsed > 0 {
+		config.Reconstruction.ParallelThreshold = parsed
+	}
+
+	if parallelWorkers := os.Getenv("PARALLEL_WORKERS"); parallelWorkers != "" {
+		if parsed, err := strconv.Atoi(parallelWorkers); err == nil && parsed > 0 {
+			config.Reconstruction.ParallelWorkers = parsed
+		}
+	}
+
+	processingTimeout := getEnvWithDefault("PROCESSING_TIMEOUT", "30s")
+	if parsed, err := time.ParseDuration(processingTimeout); err == nil && parsed > 0 {
+		config.Reconstruction.ProcessingTimeout = parsed
+	}
+
+	// Configure the result cache
+	cacheMaxEntries := getEnvWithDefault("CACHE_MAX_ENTRIES", "10000")
+	if parsed, err := strconv.Atoi(cacheMaxEntries); err == nil && parsed >= 0 {
+		config.Cache.MaxEntries = parsed
+	}
+
+	cacheMaxTickets := getEnvWithDefault("CACHE_MAX_TICKETS", "10000")
+	if parsed, err := strconv.Atoi(cacheMaxTickets); err == nil && parsed > 0 {
+		config.Cache.MaxTickets = parsed
+	}
+
+	cacheTTL := getEnvWithDefault("CACHE_TTL", "10m")
+	if parsed, err := time.ParseDuration(cacheTTL); err == nil && parsed > 0 {
+		config.Cache.TTL = parsed
+	}
+
+	// Configure request coalescing
+	coalesceMaxTickets := getEnvWithDefault("COALESCE_MAX_TICKETS", "10000")
+	if parsed, err := strconv.Atoi(coalesceMaxTickets); err == nil && parsed >= 0 {
+		config.Coalescing.MaxTickets = parsed
+	}
+
+	// Configure idempotency keys
+	idempotencyTTL := getEnvWithDefault("IDEMPOTENCY_TTL", "24h")
+	if parsed, err := time.ParseDuration(idempotencyTTL); err == nil && parsed >= 0 {
+		config.Idempotency.TTL = parsed
+	}
+
+	idempotencyMaxBody := getEnvWithDefault("IDEMPOTENCY_MAX_BODY_BYTES", "10485760")
+	if parsed, err := strconv.ParseInt(idempotencyMaxBody, 10, 64); err == nil && parsed > 0 {
+		config.Idempotency.MaxBodyBytes = parsed
+	}
+
+	idempotencyMaxKeys := getEnvWithDefault("IDEMPOTENCY_MAX_KEYS", "10000")
+	if parsed, err := strconv.Atoi(idempotencyMaxKeys); err == nil && parsed > 0 {
+		config.Idempotency.MaxKeys = parsed
+	}
+
+	idempotencyMaxClientKeys := getEnvWithDefault("IDEMPOTENCY_MAX_KEYS_PER_CLIENT", "100")
+	if parsed, err := strconv.Atoi(idempotencyMaxClientKeys); err == nil && parsed > 0 {
+		config.Idempotency.MaxKeysPerClient = parsed
+	}
+
+	idempotencyMaxStored := getEnvWithDefault("IDEMPOTENCY_MAX_STORED_BYTES", "268435456")
+	if parsed, err := strconv.ParseInt(idempotencyMaxStored, 10, 64); err == nil && parsed > 0 {
+		config.Idempotency.MaxStoredBytes = parsed
+	}
+
 	if err := validateConfig(config); err != nil {
 		return nil, err
 	}
@@ -82,5 +441,76 @@ func validateConfig(config *AppConfig) error {
 		return fmt.Errorf("worker count must be greater than zero")
 	}
 
+	switch config.WorkerPool.Executor {
+	case ExecutorAnts, ExecutorSemaphore, ExecutorInline:
+	default:
+		return fmt.Errorf("unknown executor %q", config.WorkerPool.Executor)
+	}
+
+	if config.LargePool.Threshold > 0 && config.LargePool.WorkerCount <= 0 {
+		return fmt.Errorf("large pool worker count must be greater than zero")
+	}
+
+	if config.Admission.RetryAfter <= 0 {
+		return fmt.Errorf("admission retry-after must be a positive duration")
+	}
+
+	for key, class := range config.Priority.APIKeys {
+		if class != PriorityInteractive && class != PriorityBulk {
+			return fmt.Errorf("unknown priority %q for API key %q", class, key)
+		}
+	}
+
+	if config.Batch.MaxItems <= 0 {
+		return fmt.Errorf("max batch size must be greater than zero")
+	}
+
+	if config.Stream.MaxInFlight <= 0 || config.Stream.MaxLineBytes <= 0 {
+		return fmt.Errorf("stream in-flight and line size limits must be greater than zero")
+	}
+
+	if config.Jobs.QueueSize <= 0 || config.Jobs.Workers <= 0 || config.Jobs.MaxItems <= 0 {
+		return fmt.Errorf("job queue size, workers and max items must be greater than zero")
+	}
+
+	if config.Jobs.ResultRetention <= 0 {
+		return fmt.Errorf("job result retention must be a positive duration")
+	}
+
+	if config.Webhook.MaxAttempts <= 0 {
+		return fmt.Errorf("webhook max attempts must be greater than zero")
+	}
+
+	if config.Webhook.InitialBackoff <= 0 || config.Webhook.MaxBackoff <= 0 || config.Webhook.Timeout <= 0 {
+		return fmt.Errorf("webhook backoff and timeout must be positive durations")
+	}
+
+	if config.Reconstruction.ParallelThreshold <= 0 {
+		return fmt.Errorf("parallel threshold must be greater than zero")
+	}
+
+	if config.Reconstruction.ProcessingTimeout <= 0 {
+		return fmt.Errorf("processing timeout must be a positive duration")
+	}
+
+	if config.Cache.MaxEntries > 0 && (config.Cache.MaxTickets <= 0 || config.Cache.TTL <= 0) {
+		return fmt.Errorf("cache max tickets and TTL must be positive when the cache is enabled")
+	}
+
+	idempotency := config.Idempotency
+	if idempotency.TTL > 0 && (idempotency.MaxBodyBytes <= 0 || idempotency.MaxKeys <= 0 || idempotency.MaxKeysPerClient <= 0 || idempotency.MaxStoredBytes <= 0) {
+		return fmt.Errorf("idempotency limits must be positive when idempotency keys are enabled")
+	}
+
+	switch config.Storage.Backend {
+	case StorageMemory:
+	case StorageFile:
+		if config.Storage.Path == "" {
+			return fmt.Errorf("storage path is required by the file backend")
+		}
+	default:
+		return fmt.Errorf("unknown storage backend %q", config.Storage.Backend)
+	}
+
 	return nil
 }
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"flight-itinerary-api/i18n"
+	"flight-itinerary-api/models"
+	"flight-itinerary-api/services"
+)
+
+// mimeApplicationProblemJSON is the content type of RFC 7807 problem details
+const mimeApplicationProblemJSON = "application/problem+json"
+
+// Language negotiation headers
+const (
+	headerAcceptLanguage  = "Accept-Language"
+	headerContentLanguage = "Content-Language"
+)
+
+// problemTypeBase prefixes error codes to form the type URI of their problems
+const problemTypeBase = "/problems/"
+
+// statusClientClosedRequest reports a request abandoned by its client before it completed
+const statusClientClosedRequest = 499
+
+// kindStatus maps service error kinds to HTTP statuses
+var kindStatus = map[services.ErrorKind]int{
+	services.KindInvalidInput:       http.StatusBadRequest,
+	services.KindNotFound:           http.StatusNotFound,
+	services.KindConflict:           http.StatusConflict,
+	services.KindUnavailable:        http.StatusServiceUnavailable,
+	services.KindTimeout:            http.StatusGatewayTimeout,
+	services.KindCancelled:          statusClientClosedRequest,
+	services.KindUnprocessable:      http.StatusUnprocessableEntity,
+	services.KindPreconditionFailed: http.StatusPreconditionFailed,
+	services.KindInternal:           http.StatusInternalServerError,
+}
+
+// HTTPErrorHandler renders every error returned by handlers and middleware as an
+// application/problem+json response in the language negotiated with the client
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	problem := NewProblem(err, requestLanguage(c))
+	problem.Instance = c.Request().URL.Path
+
+	if c.Request().Method == http.MethodHead {
+		_ = c.NoContent(problem.Status)
+		return
+	}
+
+	body, marshalErr := json.Marshal(problem)
+	if marshalErr != nil {
+		_ = c.NoContent(problem.Status)
+		return
+	}
+	_ = c.Blob(problem.Status, mimeApplicationProblemJSON, body)
+}
+
+// NewProblem describes err as problem details with a stable type and code, and a detail
+// message in language. Details of internal errors are not disclosed.
+func NewProblem(err error, language string) models.Problem {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		code := strings.ReplaceAll(strings.ToLower(http.StatusText(httpErr.Code)), " ", "_")
+		detail, found := i18n.Message(language, code)
+		if !found {
+			detail = fmt.Sprint(httpErr.Message)
+		}
+		return models.Problem{
+			Type:   problemTypeBase + code,
+			Title:  http.StatusText(httpErr.Code),
+			Status: httpErr.Code,
+			Detail: detail,
+			Code:   code,
+		}
+	}
+
+	kind := services.ErrorKindOf(err)
+	status := kindStatus[kind]
+	code := services.ErrorCode(err)
+
+	detail := localize(language, err)
+	if kind == services.KindInternal {
+		detail = localize(language, services.ErrInternal)
+	}
+
+	title := http.StatusText(status)
+	if status == statusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+
+	return models.Problem{
+		Type:   problemTypeBase + code,
+		Title:  title,
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// requestLanguage negotiates the language of the messages in the response from the request's
+// Accept-Language header, and declares it on the response
+func requestLanguage(c echo.Context) string {
+	language := i18n.Negotiate(c.Request().Header.Get(headerAcceptLanguage))
+
+	header := c.Response().Header()
+	header.Add(echo.HeaderVary, headerAcceptLanguage)
+	header.Set(headerContentLanguage, language)
+	return language
+}
+
+// localize renders the message of err in language from its code, falling back to the
+// error's own message for codes no catalog knows
+func localize(language string, err error) string {
+	var (
+		args          []interface{}
+		validationErr *models.ValidationError
+	)
+	if errors.As(err, &validationErr) {
+		args = validationErr.Args
+	}
+
+	if message, found := i18n.Message(language, services.ErrorCode(err), args...); found {
+		return message
+	}
+	return err.Error()
+}
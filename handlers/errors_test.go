@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"flight-itinerary-api/models"
+	"flight-itinerary-api/services"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	e := echo.New()
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{
+			name:       "validation error",
+			err:        models.ErrInvalidFormat,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_format",
+			wantDetail: "invalid request format",
+		},
+		{
+			name:       "finished job",
+			err:        services.ErrJobFinished,
+			wantStatus: http.StatusConflict,
+			wantCode:   "job_finished",
+			wantDetail: services.ErrJobFinished.Error(),
+		},
+		{
+			name:       "wrapped overload",
+			err:        fmt.Errorf("%w: pool closed", services.ErrOverloaded),
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   "overloaded",
+			wantDetail: services.ErrOverloaded.Error(),
+		},
+		{
+			name:       "processing timeout",
+			err:        services.ErrProcessingTimeout,
+			wantStatus: http.StatusGatewayTimeout,
+			wantCode:   "processing_timeout",
+			wantDetail: services.ErrProcessingTimeout.Error(),
+		},
+		{
+			name:       "unknown job",
+			err:        services.ErrJobNotFound,
+			wantStatus: http.StatusNotFound,
+			wantCode:   "job_not_found",
+			wantDetail: services.ErrJobNotFound.Error(),
+		},
+		{
+			name:       "recovered panic",
+			err:        &services.PanicError{Value: "secret request data"},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "internal_error",
+			wantDetail: services.ErrInternal.Error(),
+		},
+		{
+			name:       "unknown error details are hidden",
+			err:        errors.New("connection to 10.0.0.1 refused"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "internal_error",
+			wantDetail: services.ErrInternal.Error(),
+		},
+		{
+			name:       "cancelled request",
+			err:        context.Canceled,
+			wantStatus: 499,
+			wantCode:   "request_cancelled",
+			wantDetail: "request cancelled",
+		},
+		{
+			name:       "echo error",
+			err:        echo.ErrNotFound,
+			wantStatus: http.StatusNotFound,
+			wantCode:   "not_found",
+			wantDetail: "resource not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/itinerary", nil)
+			rec := httptest.NewRecorder()
+			HTTPErrorHandler(tt.err, e.NewContext(req, rec))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get(echo.HeaderContentType); got != "application/problem+json" {
+				t.Errorf("content type = %q, want application/problem+json", got)
+			}
+
+			var problem models.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Failed to decode problem: %v", err)
+			}
+			want := models.Problem{
+				Type:     "/problems/" + tt.wantCode,
+				Title:    problem.Title,
+				Status:   tt.wantStatus,
+				Detail:   tt.wantDetail,
+				Instance: "/api/itinerary",
+				Code:     tt.wantCode,
+			}
+			if problem != want {
+				t.Errorf("problem = %+v, want %+v", problem, want)
+			}
+			if problem.Title == "" {
+				t.Error("problem has no title")
+			}
+		})
+	}
+}
+
+func TestHTTPErrorHandlerCommittedResponse(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/itineraries/stream", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// A streaming response already started cannot be replaced by a problem
+	c.Response().WriteHeader(http.StatusOK)
+	HTTPErrorHandler(services.ErrOverloaded, c)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %v, want %v", rec.Code, http.StatusOK)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("body = %q, want empty", rec.Body.String())
+	}
+}
+
+func TestHTTPErrorHandlerLocalized(t *testing.T) {
+	e := echo.New()
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		err            error
+		wantLanguage   string
+		wantDetail     string
+	}{
+		{
+			name:           "french validation error",
+			acceptLanguage: "fr-FR,fr;q=0.9,en;q=0.8",
+			err:            models.ErrInvalidFormat,
+			wantLanguage:   "fr",
+			wantDetail:     "format de requête invalide",
+		},
+		{
+			name:           "arabic message with arguments",
+			acceptLanguage: "ar",
+			err:            models.StreamLineTooLong(3, 128),
+			wantLanguage:   "ar",
+			wantDetail:     "مجموعة التذاكر في السطر 3 تتجاوز 128 بايت",
+		},
+		{
+			name:           "spanish preferred by quality",
+			acceptLanguage: "de;q=0.9, es;q=0.8, fr;q=0.5",
+			err:            services.ErrJobNotFound,
+			wantLanguage:   "es",
+			wantDetail:     "trabajo no encontrado",
+		},
+		{
+			name:           "unsupported language falls back to english",
+			acceptLanguage: "de-DE",
+			err:            services.ErrJobNotFound,
+			wantLanguage:   "en",
+			wantDetail:     "job not found",
+		},
+		{
+			name:           "internal error details stay hidden",
+			acceptLanguage: "fr",
+			err:            errors.New("connection to 10.0.0.1 refused"),
+			wantLanguage:   "fr",
+			wantDetail:     "erreur interne",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/jobs/unknown", nil)
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			rec := httptest.NewRecorder()
+			HTTPErrorHandler(tt.err, e.NewContext(req, rec))
+
+			if got := rec.Header().Get("Content-Language"); got != tt.wantLanguage {
+				t.Errorf("Content-Language = %q, want %q", got, tt.wantLanguage)
+			}
+			if got := rec.Header().Get(echo.HeaderVary); got != "Accept-Language" {
+				t.Errorf("Vary = %q, want Accept-Language", got)
+			}
+
+			var problem models.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Failed to decode problem: %v", err)
+			}
+			if problem.Detail != tt.wantDetail {
+				t.Errorf("detail = %q, want %q", problem.Detail, tt.wantDetail)
+			}
+		})
+	}
+}
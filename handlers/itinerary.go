@@ -1,56 +1,507 @@
 package handlers
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"flight-itinerary-api/api/middleware"
 	"flight-itinerary-api/models"
 	"flight-itinerary-api/services"
 )
 
+// mimeApplicationNDJSON is the content type of newline-delimited JSON streams
+const mimeApplicationNDJSON = "application/x-ndjson"
+
+// headerXCache reports whether an itinerary was served from the result cache
+const headerXCache = "X-Cache"
+
+// ItineraryRouteName names the route of stored itineraries, which Location headers point to
+const ItineraryRouteName = "itinerary"
+
+// Conditional request headers
+const (
+	headerETag    = "ETag"
+	headerIfMatch = "If-Match"
+)
+
+// errStreamThrottled ends a stream once its client has run out of rate limit tokens. The status
+// line is already sent by then, so it is only ever reported as a trailing error line.
+var errStreamThrottled = services.NewError(services.KindUnavailable, "too_many_requests", "too many requests")
+
 // ItineraryHandler handles HTTP requests for flight itinerary operations
 type ItineraryHandler struct {
 	service *services.ItineraryService
+	store   *services.ItineraryStore
 }
 
 // NewItineraryHandler creates a new instance of ItineraryHandler
-func NewItineraryHandler(service *services.ItineraryService) *ItineraryHandler {
+func NewItineraryHandler(service *services.ItineraryService, store *services.ItineraryStore) *ItineraryHandler {
 	return &ItineraryHandler{
 		service: service,
+		store:   store,
 	}
 }
 
-// ProcessItinerary handles the POST request to process flight tickets and return an ordered itinerary
+// ProcessItinerary handles the POST request to process flight tickets and return an ordered itinerary.
+// Tickets are validated and inserted into the flight graph while the body is being read, and
+// the itinerary is written out as it is encoded. With store=true the itinerary is stored
+// instead, and returned with its ID and the tickets it was reconstructed from.
 func (h *ItineraryHandler) ProcessItinerary(c echo.Context) error {
-	var request models.ItineraryRequest
+	var (
+		request models.ItineraryRequest
+		tickets []models.TicketPair
+	)
+
+	req := c.Request()
+	if req.ContentLength != 0 && !strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		return echo.ErrUnsupportedMediaType
+	}
+
+	store := false
+	if value := c.QueryParam("store"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return models.ErrInvalidStore
+		}
+		store = parsed
+	}
+
+	// Report how the result cache served the itinerary
+	ctx := services.WithCacheStatus(req.Context(), func(status services.CacheStatus) {
+		c.Response().Header().Set(headerXCache, string(status))
+	})
+
+	// Parse, validate and process the request body with context
+	itinerary, err := h.service.ReconstructFromSource(ctx, func(visit func(src, dst string) error) error {
+		return models.DecodeItineraryRequest(req.Body, &request, func(src, dst string) error {
+			// Only itineraries being stored need their tickets kept
+			if store {
+				tickets = append(tickets, models.TicketPair{src, dst})
+			}
+			return visit(src, dst)
+		})
+	})
+	if err != nil {
+		// Tell the client when a shed request is worth retrying
+		if errors.Is(err, services.ErrOverloaded) {
+			setRetryAfter(c, h.service.RetryAfter())
+		}
+		return err
+	}
+
+	if store {
+		request.Tickets = tickets
+		stored, err := h.store.Save(req.Context(), &request, itinerary)
+		if err != nil {
+			return err
+		}
+		c.Response().Header().Set(echo.HeaderLocation, c.Echo().Reverse(ItineraryRouteName, stored.ID))
+		setETag(c, stored.Revision)
+		return c.JSON(http.StatusCreated, stored)
+	}
+
+	// Return the response
+	return writeItinerary(c.Response(), itinerary)
+}
+
+// ListItineraries handles the GET request listing the stored itineraries matching the query
+// parameters, a page at a time
+func (h *ItineraryHandler) ListItineraries(c echo.Context) error {
+	query, err := parseItineraryQuery(c)
+	if err != nil {
+		return err
+	}
+
+	list, err := h.store.List(c.Request().Context(), query)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, list)
+}
+
+// GetItinerary handles the GET request returning the latest revision of a stored itinerary
+func (h *ItineraryHandler) GetItinerary(c echo.Context) error {
+	stored, err := h.store.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	setETag(c, stored.Revision)
+	return c.JSON(http.StatusOK, stored)
+}
+
+// PatchItinerary handles the PATCH request adding, removing or replacing tickets of a stored
+// itinerary. With an If-Match header, the update only applies to the revision it names.
+func (h *ItineraryHandler) PatchItinerary(c echo.Context) error {
+	var patch models.ItineraryPatch
+
+	// Parse request body
+	if err := c.Bind(&patch); err != nil {
+		return models.ErrInvalidFormat
+	}
+
+	var ifMatch func(int) bool
+	if header := c.Request().Header.Get(headerIfMatch); header != "" {
+		ifMatch = func(revision int) bool {
+			return matchesETag(header, revision)
+		}
+	}
+
+	stored, err := h.store.Update(c.Request().Context(), c.Param("id"), &patch, ifMatch)
+	if err != nil {
+		return err
+	}
+
+	setETag(c, stored.Revision)
+	return c.JSON(http.StatusOK, stored)
+}
+
+// GetRevisions handles the GET request returning every revision of a stored itinerary
+func (h *ItineraryHandler) GetRevisions(c echo.Context) error {
+	revisions, err := h.store.Revisions(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	response := models.ItineraryRevisions{Revisions: make([]models.StoredItinerary, len(revisions))}
+	for i, revision := range revisions {
+		response.Revisions[i] = *revision
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// GetRevision handles the GET request returning a single revision of a stored itinerary
+func (h *ItineraryHandler) GetRevision(c echo.Context) error {
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		return services.ErrRevisionNotFound
+	}
+
+	stored, err := h.store.Revision(c.Request().Context(), c.Param("id"), revision)
+	if err != nil {
+		return err
+	}
+
+	setETag(c, stored.Revision)
+	return c.JSON(http.StatusOK, stored)
+}
+
+// DiffRevisions handles the GET request comparing two revisions of a stored itinerary, by
+// default the latest one and the one before it
+func (h *ItineraryHandler) DiffRevisions(c echo.Context) error {
+	revisions := [2]int{}
+	for i, name := range []string{"from", "to"} {
+		value := c.QueryParam(name)
+		if value == "" {
+			continue
+		}
+		revision, err := strconv.Atoi(value)
+		if err != nil || revision < 1 {
+			return models.InvalidQueryParameter(name)
+		}
+		revisions[i] = revision
+	}
+
+	diff, err := h.store.Diff(c.Request().Context(), c.Param("id"), revisions[0], revisions[1])
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, diff)
+}
+
+// DiffItineraries handles the POST request comparing the itineraries of two ticket sets
+func (h *ItineraryHandler) DiffItineraries(c echo.Context) error {
+	var request models.ItineraryDiffRequest
 
 	// Parse request body
 	if err := c.Bind(&request); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request format",
-		})
+		return models.ErrInvalidFormat
 	}
 
-	// Validate request
-	if err := request.Validate(); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": err.Error(),
-		})
+	// The rate limiter already charged one token for the request itself
+	if err := middleware.ConsumeRateLimit(c, 1); err != nil {
+		return err
 	}
 
-	// Process the itinerary with context
-	itinerary, err := h.service.ReconstructItinerary(c.Request().Context(), &request)
+	diff, err := h.service.Diff(c.Request().Context(), &request)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": err.Error(),
-		})
+		// Tell the client when a shed request is worth retrying
+		if errors.Is(err, services.ErrOverloaded) {
+			setRetryAfter(c, h.service.RetryAfter())
+		}
+		return err
 	}
+	return c.JSON(http.StatusOK, diff)
+}
 
-	// Return the response
-	response := models.ItineraryResponse{
-		Itinerary: itinerary,
+// ProcessBatch handles the POST request to process several independent ticket sets at once
+func (h *ItineraryHandler) ProcessBatch(c echo.Context) error {
+	var request models.BatchItineraryRequest
+
+	// Parse request body
+	if err := c.Bind(&request); err != nil {
+		return models.ErrInvalidFormat
+	}
+
+	// Validate the batch envelope
+	if err := request.Validate(batchLimit(c, h.service.MaxBatchSize())); err != nil {
+		return err
+	}
+
+	// The rate limiter already charged one token for the request itself
+	if err := middleware.ConsumeRateLimit(c, len(request.Requests)-1); err != nil {
+		return err
+	}
+
+	// Process every ticket set, collecting per-item outcomes
+	results := h.service.ReconstructBatch(c.Request().Context(), request.Requests)
+
+	language := requestLanguage(c)
+	response := models.BatchItineraryResponse{
+		Results: make([]models.BatchItemResult, len(results)),
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			// Tell the client when shed items are worth retrying
+			if errors.Is(result.Err, services.ErrOverloaded) {
+				setRetryAfter(c, h.service.RetryAfter())
+			}
+			response.Results[i].Error = localize(language, result.Err)
+			response.Results[i].Code = services.ErrorCode(result.Err)
+			continue
+		}
+		response.Results[i].Itinerary = result.Itinerary
 	}
 
 	return c.JSON(http.StatusOK, response)
 }
+
+// ProcessStream handles the POST request carrying newline-delimited JSON ticket sets and streams
+// one NDJSON result line per ticket set as soon as it is computed
+func (h *ItineraryHandler) ProcessStream(c echo.Context) error {
+	req := c.Request()
+	res := c.Response()
+
+	// Results are written while the body is still being read
+	if err := http.NewResponseController(res).EnableFullDuplex(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Streaming not supported")
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	// Lines are charged from the reader goroutine, after the status line is sent
+	charge := middleware.RateLimitCharger(c)
+	requests := make(chan services.StreamRequest)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(requests)
+		readErr <- readStreamRequests(ctx, req.Body, h.service.MaxStreamLineBytes(), requests, func() error {
+			if !charge(1) {
+				return errStreamThrottled
+			}
+			return nil
+		})
+	}()
+
+	language := requestLanguage(c)
+	res.Header().Set(echo.HeaderContentType, mimeApplicationNDJSON)
+	res.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(res)
+	err := h.service.ReconstructStream(ctx, requests, func(result services.StreamResult) error {
+		line := models.StreamItemResult{Index: result.Index}
+		if result.Err != nil {
+			line.Error = localize(language, result.Err)
+			line.Code = services.ErrorCode(result.Err)
+		} else {
+			line.Itinerary = result.Itinerary
+		}
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+		res.Flush()
+		return nil
+	})
+
+	// Unblock the reader if processing stopped early
+	cancel()
+	if rerr := <-readErr; rerr != nil && err == nil {
+		err = rerr
+	}
+
+	// The status line is already sent, so failures are reported as a trailing error line
+	if err != nil && !errors.Is(err, context.Canceled) {
+		_ = encoder.Encode(map[string]string{"error": localize(language, err), "code": services.ErrorCode(err)})
+	}
+	return nil
+}
+
+// parseItineraryQuery reads the filters, cursor and limit of an itinerary listing
+func parseItineraryQuery(c echo.Context) (*models.ItineraryQuery, error) {
+	query := &models.ItineraryQuery{
+		Airport:     c.QueryParam("airport"),
+		Origin:      c.QueryParam("origin"),
+		Destination: c.QueryParam("destination"),
+		Passenger:   c.QueryParam("passenger"),
+		TravelFrom:  c.QueryParam("travel_date_from"),
+		TravelTo:    c.QueryParam("travel_date_to"),
+		Cursor:      c.QueryParam("cursor"),
+		Limit:       models.DefaultItineraryListLimit,
+	}
+
+	if value := c.QueryParam("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, models.InvalidQueryParameter("limit")
+		}
+		query.Limit = limit
+	}
+
+	for _, bound := range []struct {
+		name string
+		time *time.Time
+	}{
+		{"created_after", &query.CreatedAfter},
+		{"created_before", &query.CreatedBefore},
+	} {
+		value := c.QueryParam(bound.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, models.InvalidQueryParameter(bound.name)
+		}
+		*bound.time = parsed
+	}
+
+	return query, nil
+}
+
+// readStreamRequests decodes one ticket set per non-empty line of body and sends it on requests.
+// Lines that are not valid ticket sets are forwarded with an error so they get their own result line.
+// Every line but the first, already paid for by the request, is paid for with charge; reading
+// stops at the first line charge refuses.
+func readStreamRequests(ctx context.Context, body io.Reader, maxLineBytes int, requests chan<- services.StreamRequest, charge func() error) error {
+	// The scanner honours the larger of the initial buffer and the limit, so never start above it
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, min(bufio.MaxScanTokenSize, maxLineBytes)), maxLineBytes)
+
+	index := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if index > 0 {
+			if err := charge(); err != nil {
+				return err
+			}
+		}
+
+		item := services.StreamRequest{Index: index}
+		if err := json.Unmarshal(line, &item.Request); err != nil {
+			item.Err = models.ErrInvalidFormat
+		}
+		index++
+
+		select {
+		case requests <- item:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return models.StreamLineTooLong(index+1, maxLineBytes)
+		}
+		return err
+	}
+	return nil
+}
+
+// writeItinerary streams an ItineraryResponse without buffering the whole document
+func writeItinerary(res *echo.Response, itinerary []string) error {
+	res.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	res.WriteHeader(http.StatusOK)
+
+	w := bufio.NewWriter(res)
+	w.WriteString(`{"itinerary":[`)
+	for i, airport := range itinerary {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		writeJSONString(w, airport)
+	}
+	w.WriteString("]}\n")
+
+	return w.Flush()
+}
+
+// writeJSONString writes s as a JSON string, taking a copy-free path for the plain ASCII
+// codes that make up almost every itinerary
+func writeJSONString(w *bufio.Writer, s string) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c > 0x7e || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			quoted, _ := json.Marshal(s)
+			w.Write(quoted)
+			return
+		}
+	}
+
+	w.WriteByte('"')
+	w.WriteString(s)
+	w.WriteByte('"')
+}
+
+// etag returns the entity tag of a revision of a stored itinerary
+func etag(revision int) string {
+	return `"` + strconv.Itoa(revision) + `"`
+}
+
+// setETag sets the ETag header to the entity tag of revision
+func setETag(c echo.Context, revision int) {
+	c.Response().Header().Set(headerETag, etag(revision))
+}
+
+// matchesETag reports whether an If-Match header matches revision: it lists its entity tag,
+// or is "*". Weak tags never match, as If-Match requires a strong comparison.
+func matchesETag(header string, revision int) bool {
+	want := etag(revision)
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag == "*" || tag == want {
+			return true
+		}
+	}
+	return false
+}
+
+// batchLimit returns the most ticket sets a single request may carry: maxItems, lowered to the
+// burst of the client's rate limit, since every ticket set is charged a token and a larger
+// batch could never be let through
+func batchLimit(c echo.Context, maxItems int) int {
+	if burst := middleware.RateLimitBurst(c); burst > 0 && (maxItems <= 0 || burst < maxItems) {
+		return burst
+	}
+	return maxItems
+}
+
+// setRetryAfter sets the Retry-After header to delay, rounded up to whole seconds
+func setRetryAfter(c echo.Context, delay time.Duration) {
+	seconds := int64((delay + time.Second - 1) / time.Second)
+	c.Response().Header().Set(echo.HeaderRetryAfter, strconv.FormatInt(seconds, 10))
+}
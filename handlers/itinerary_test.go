@@ -5,13 +5,17 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"flight-itinerary-api/api/middleware"
 	"flight-itinerary-api/config"
 	"flight-itinerary-api/models"
+	"flight-itinerary-api/repository"
 	"flight-itinerary-api/services"
 )
 
@@ -26,7 +30,7 @@ func TestProcessItinerary(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	service := services.NewItineraryService(ctx, cfg)
-	handler := NewItineraryHandler(service)
+	handler := NewItineraryHandler(service, services.NewItineraryStore(repository.NewMemoryRepository()))
 
 	tests := []struct {
 		name       string
@@ -85,15 +89,8 @@ func TestProcessItinerary(t *testing.T) {
 			c := e.NewContext(req, rec)
 
 			// Serve request
-			err = handler.ProcessItinerary(c)
-			if err != nil {
-				// Echo's error handler would normally handle this
-				he, ok := err.(*echo.HTTPError)
-				if ok {
-					rec.Code = he.Code
-				} else {
-					rec.Code = http.StatusInternalServerError
-				}
+			if err := handler.ProcessItinerary(c); err != nil {
+				HTTPErrorHandler(err, c)
 			}
 
 			// Check status code
@@ -104,7 +101,7 @@ func TestProcessItinerary(t *testing.T) {
 			// Parse response
 			var response struct {
 				Itinerary []string `json:"itinerary,omitempty"`
-				Error     string   `json:"error,omitempty"`
+				Code      string   `json:"code,omitempty"`
 			}
 			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
 				t.Fatalf("Failed to decode response: %v", err)
@@ -112,15 +109,15 @@ func TestProcessItinerary(t *testing.T) {
 
 			// Verify response structure
 			if tt.wantErr {
-				if response.Error == "" {
-					t.Error("Expected error in response, got none")
+				if response.Code == "" {
+					t.Error("Expected error code in response, got none")
 				}
 				if response.Itinerary != nil {
 					t.Error("Expected no itinerary in error response")
 				}
 			} else {
-				if response.Error != "" {
-					t.Errorf("Unexpected error in response: %v", response.Error)
+				if response.Code != "" {
+					t.Errorf("Unexpected error in response: %v", response.Code)
 				}
 				if response.Itinerary == nil {
 					t.Error("Expected itinerary in successful response")
@@ -129,3 +126,777 @@ func TestProcessItinerary(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessBatch(t *testing.T) {
+	// Setup
+	e := echo.New()
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 5,
+		},
+		Batch: config.BatchConfig{
+			MaxItems: 2,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := services.NewItineraryService(ctx, cfg)
+	handler := NewItineraryHandler(service, services.NewItineraryStore(repository.NewMemoryRepository()))
+
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantResults []models.BatchItemResult
+	}{
+		{
+			name:       "mixed valid and invalid ticket sets",
+			body:       `{"requests":[{"tickets":[["LAX","JFK"],["SFO","LAX"]]},{"tickets":[["SFO","LAX"],["JFK","MCO"]]}]}`,
+			wantStatus: http.StatusOK,
+			wantResults: []models.BatchItemResult{
+				{Itinerary: []string{"SFO", "LAX", "JFK"}},
+				{Error: "invalid tickets: multiple starting points found", Code: "multiple_starts"},
+			},
+		},
+		{
+			name:       "empty batch",
+			body:       `{"requests":[]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "batch too large",
+			body:       `{"requests":[{"tickets":[["SFO","LAX"]]},{"tickets":[["SFO","LAX"]]},{"tickets":[["SFO","LAX"]]}]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/itineraries/batch", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			if err := handler.ProcessBatch(c); err != nil {
+				HTTPErrorHandler(err, c)
+			}
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ProcessBatch() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if tt.wantResults == nil {
+				return
+			}
+
+			var response models.BatchItineraryResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if !reflect.DeepEqual(response.Results, tt.wantResults) {
+				t.Errorf("ProcessBatch() results = %+v, want %+v", response.Results, tt.wantResults)
+			}
+		})
+	}
+}
+
+func TestProcessBatchRateLimit(t *testing.T) {
+	// Setup
+	e := echo.New()
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 2,
+		},
+		Batch: config.BatchConfig{
+			MaxItems: 10,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := services.NewItineraryService(ctx, cfg)
+	handler := NewItineraryHandler(service, services.NewItineraryStore(repository.NewMemoryRepository()))
+	limiter := middleware.NewRateLimiterMiddleware(ctx, &config.RateLimiterConfig{Enabled: true, MaxReqsPerMin: 3})
+	processBatch := limiter.Middleware()(handler.ProcessBatch)
+
+	send := func(items int) *httptest.ResponseRecorder {
+		body := `{"requests":[` + strings.Repeat(`{"tickets":[["SFO","LAX"]]},`, items-1) + `{"tickets":[["SFO","LAX"]]}]}`
+		req := httptest.NewRequest(http.MethodPost, "/itineraries/batch", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		if err := processBatch(c); err != nil {
+			HTTPErrorHandler(err, c)
+		}
+		return rec
+	}
+
+	// A batch larger than the burst could never be charged, so it is refused outright
+	rec := send(4)
+	var problem models.Problem
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest || problem.Code != "too_many_ticket_sets" || problem.Detail != "too many ticket sets: maximum is 3" {
+		t.Errorf("ProcessBatch(4 items) = %v %+v, want 400 too_many_ticket_sets", rec.Code, problem)
+	}
+	if got := rec.Header().Get(echo.HeaderRetryAfter); got != "" {
+		t.Errorf("ProcessBatch(4 items) Retry-After = %q, want none", got)
+	}
+
+	// The refused batch was charged a single token, leaving room for the next request
+	if rec := send(2); rec.Code != http.StatusOK {
+		t.Errorf("ProcessBatch(2 items) status = %v, want %v", rec.Code, http.StatusOK)
+	}
+
+	// Once the tokens run out, batches are throttled with a hint of when to retry
+	rec = send(2)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("ProcessBatch(2 items) status = %v, want %v", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get(echo.HeaderRetryAfter); got != "20" {
+		t.Errorf("ProcessBatch(2 items) Retry-After = %q, want %q", got, "20")
+	}
+}
+
+func TestProcessStream(t *testing.T) {
+	// Setup
+	e := echo.New()
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 5,
+		},
+		Stream: config.StreamConfig{
+			MaxInFlight:  4,
+			MaxLineBytes: 128,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := services.NewItineraryService(ctx, cfg)
+	handler := NewItineraryHandler(service, services.NewItineraryStore(repository.NewMemoryRepository()))
+
+	body := strings.Join([]string{
+		`{"tickets":[["LAX","JFK"],["SFO","LAX"]]}`,
+		``,
+		`not json`,
+		`{"tickets":[["SFO","LAX"],["JFK","MCO"]]}`,
+		`{"tickets":[["` + strings.Repeat("X", 200) + `","JFK"]]}`,
+		`{"tickets":[["SFO","JFK"]]}`,
+	}, "\n")
+
+	req := httptest.NewRequest(http.MethodPost, "/itineraries/stream", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := handler.ProcessStream(c); err != nil {
+		t.Fatalf("ProcessStream() returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("ProcessStream() status = %v, want %v", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get(echo.HeaderContentType); got != "application/x-ndjson" {
+		t.Errorf("ProcessStream() content type = %q", got)
+	}
+
+	// Result lines may arrive in any order; the oversized line ends the stream with an error line
+	results := make(map[int]models.StreamItemResult)
+	var trailingCode string
+	decoder := json.NewDecoder(rec.Body)
+	for decoder.More() {
+		var line struct {
+			Index *int `json:"index"`
+			models.BatchItemResult
+		}
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatalf("Failed to decode result line: %v", err)
+		}
+		if line.Index == nil {
+			trailingCode = line.Code
+			continue
+		}
+		results[*line.Index] = models.StreamItemResult{Index: *line.Index, BatchItemResult: line.BatchItemResult}
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("ProcessStream() returned %d result lines, want 3", len(results))
+	}
+	if !reflect.DeepEqual(results[0].Itinerary, []string{"SFO", "LAX", "JFK"}) {
+		t.Errorf("line 0 itinerary = %v", results[0].Itinerary)
+	}
+	if results[1].Error != "invalid request format" || results[1].Code != "invalid_format" {
+		t.Errorf("line 1 error = %q (%s)", results[1].Error, results[1].Code)
+	}
+	if results[2].Error == "" {
+		t.Error("line 2: expected an error for a disconnected route")
+	}
+	if trailingCode != "line_too_long" {
+		t.Errorf("trailing error code = %q, want line_too_long", trailingCode)
+	}
+}
+
+func TestProcessStreamRateLimit(t *testing.T) {
+	// Setup
+	e := echo.New()
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 2,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := services.NewItineraryService(ctx, cfg)
+	handler := NewItineraryHandler(service, services.NewItineraryStore(repository.NewMemoryRepository()))
+	limiter := middleware.NewRateLimiterMiddleware(ctx, &config.RateLimiterConfig{Enabled: true, MaxReqsPerMin: 3})
+
+	// Every line is charged a token, so the fourth line ends the stream
+	body := strings.Repeat(`{"tickets":[["SFO","LAX"]]}`+"\n", 5)
+	req := httptest.NewRequest(http.MethodPost, "/itineraries/stream", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	if err := limiter.Middleware()(handler.ProcessStream)(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("ProcessStream() returned error: %v", err)
+	}
+
+	var lines []models.BatchItemResult
+	decoder := json.NewDecoder(rec.Body)
+	for decoder.More() {
+		var line models.BatchItemResult
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatalf("Failed to decode result line: %v", err)
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) != 4 {
+		t.Fatalf("ProcessStream() returned %d lines, want 3 results and an error line", len(lines))
+	}
+	for i, line := range lines[:3] {
+		if !reflect.DeepEqual(line.Itinerary, []string{"SFO", "LAX"}) {
+			t.Errorf("line %d = %+v", i, line)
+		}
+	}
+	if lines[3].Code != "too_many_requests" || lines[3].Error != "too many requests" {
+		t.Errorf("trailing error = %+v, want too_many_requests", lines[3])
+	}
+
+	// The headers were sent with the first line and are left alone by the refusal
+	wantHeader := http.Header{
+		echo.HeaderContentType: {"application/x-ndjson"},
+		headerContentLanguage:  {"en"},
+		echo.HeaderVary:        {"Accept-Language"},
+	}
+	if !reflect.DeepEqual(rec.Header(), wantHeader) {
+		t.Errorf("ProcessStream() headers = %v, want %v", rec.Header(), wantHeader)
+	}
+}
+
+func TestProcessItineraryStreaming(t *testing.T) {
+	// Setup
+	e := echo.New()
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 5,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := services.NewItineraryService(ctx, cfg)
+	handler := NewItineraryHandler(service, services.NewItineraryStore(repository.NewMemoryRepository()))
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:        "codes needing escaping",
+			contentType: echo.MIMEApplicationJSON,
+			body:        `{"tickets":[["A\"B","LAX"],["LAX","<a>"]]}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"itinerary":["A\"B","LAX","\u003ca\u003e"]}` + "\n",
+		},
+		{
+			name:        "malformed JSON",
+			contentType: echo.MIMEApplicationJSON,
+			body:        `{"tickets":[["SFO","LAX"]`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"type":"/problems/invalid_format","title":"Bad Request","status":400,"detail":"invalid request format","instance":"/itinerary","code":"invalid_format"}`,
+		},
+		{
+			name:        "unsupported content type",
+			contentType: echo.MIMEApplicationForm,
+			body:        `tickets=SFO`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantBody:    `{"type":"/problems/unsupported_media_type","title":"Unsupported Media Type","status":415,"detail":"unsupported media type: send application/json","instance":"/itinerary","code":"unsupported_media_type"}`,
+		},
+		{
+			name:        "duplicate source detected while decoding",
+			contentType: echo.MIMEApplicationJSON,
+			body:        `{"tickets":[["SFO","LAX"],["SFO","JFK"],["JFK"]]}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"type":"/problems/duplicate_source","title":"Bad Request","status":400,"detail":"invalid tickets: multiple flights from same source","instance":"/itinerary","code":"duplicate_source"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/itinerary", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, tt.contentType)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			if err := handler.ProcessItinerary(c); err != nil {
+				HTTPErrorHandler(err, c)
+			}
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ProcessItinerary() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if rec.Body.String() != tt.wantBody {
+				t.Errorf("ProcessItinerary() body = %s, want %s", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestProcessItineraryOverloaded(t *testing.T) {
+	// Setup
+	e := echo.New()
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+		Admission: config.AdmissionConfig{
+			NonBlocking: true,
+			RetryAfter:  1500 * time.Millisecond,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := services.NewItineraryService(ctx, cfg)
+	handler := NewItineraryHandler(service, services.NewItineraryStore(repository.NewMemoryRepository()))
+
+	body := `{"tickets":[["SFO","LAX"]]}`
+
+	// Hold the only worker with a request stalled in its reconstruction
+	started := make(chan struct{})
+	release := make(chan struct{})
+	stalledCtx := services.WithProgress(context.Background(), func(_ int, stage services.Stage, _ int) {
+		if stage == services.StageGraphBuilt {
+			close(started)
+			<-release
+		}
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest(http.MethodPost, "/itinerary", strings.NewReader(body)).WithContext(stalledCtx)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		_ = handler.ProcessItinerary(e.NewContext(req, httptest.NewRecorder()))
+	}()
+	<-started
+
+	req := httptest.NewRequest(http.MethodPost, "/itinerary", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if err := handler.ProcessItinerary(c); err != nil {
+		HTTPErrorHandler(err, c)
+	}
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("ProcessItinerary() status = %v, want %v", rec.Code, http.StatusServiceUnavailable)
+	}
+	if got := rec.Header().Get(echo.HeaderRetryAfter); got != "2" {
+		t.Errorf("Retry-After = %q, want %q", got, "2")
+	}
+
+	close(release)
+	<-done
+}
+
+func TestProcessItineraryCache(t *testing.T) {
+	// Setup
+	e := echo.New()
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+		Cache: config.CacheConfig{
+			MaxEntries: 10,
+			MaxTickets: 10,
+			TTL:        time.Minute,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := services.NewItineraryService(ctx, cfg)
+	handler := NewItineraryHandler(service, services.NewItineraryStore(repository.NewMemoryRepository()))
+
+	for _, tt := range []struct {
+		body      string
+		bypass    bool
+		wantCache string
+	}{
+		{`{"tickets":[["SFO","LAX"],["LAX","JFK"]]}`, false, "MISS"},
+		{`{"tickets":[["LAX","JFK"],["SFO","LAX"]]}`, false, "HIT"},
+		{`{"tickets":[["SFO","LAX"],["LAX","JFK"]]}`, true, "BYPASS"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/itinerary", strings.NewReader(tt.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if tt.bypass {
+			req = req.WithContext(services.WithCacheBypass(req.Context()))
+		}
+		rec := httptest.NewRecorder()
+		if err := handler.ProcessItinerary(e.NewContext(req, rec)); err != nil {
+			t.Fatalf("ProcessItinerary() returned error: %v", err)
+		}
+
+		if got := rec.Header().Get("X-Cache"); got != tt.wantCache {
+			t.Errorf("X-Cache = %q, want %q", got, tt.wantCache)
+		}
+		if want := `{"itinerary":["SFO","LAX","JFK"]}` + "\n"; rec.Body.String() != want {
+			t.Errorf("ProcessItinerary() body = %s, want %s", rec.Body.String(), want)
+		}
+	}
+}
+
+func TestStoredItinerary(t *testing.T) {
+	// Setup
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := services.NewItineraryService(ctx, cfg)
+	handler := NewItineraryHandler(service, services.NewItineraryStore(repository.NewMemoryRepository()))
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.POST("/api/itinerary", handler.ProcessItinerary)
+	e.GET("/api/itineraries/:id", handler.GetItinerary).Name = ItineraryRouteName
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodPost, "/api/itinerary?store=true", `{"tickets":[["LAX","JFK"],["SFO","LAX"]]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("store status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var stored models.StoredItinerary
+	if err := json.Unmarshal(rec.Body.Bytes(), &stored); err != nil {
+		t.Fatalf("Failed to unmarshal stored itinerary: %v", err)
+	}
+	if !reflect.DeepEqual(stored.Itinerary, []string{"SFO", "LAX", "JFK"}) || len(stored.Tickets) != 2 {
+		t.Errorf("stored itinerary = %+v", stored)
+	}
+	location := rec.Header().Get(echo.HeaderLocation)
+	if want := "/api/itineraries/" + stored.ID; location != want {
+		t.Errorf("Location = %q, want %q", location, want)
+	}
+
+	// The stored itinerary is retrieved from its location
+	rec = serve(http.MethodGet, location, "")
+	var got models.StoredItinerary
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d %s", location, rec.Code, rec.Body.String())
+	}
+	if !reflect.DeepEqual(got, stored) {
+		t.Errorf("GET %s = %+v, want %+v", location, got, stored)
+	}
+
+	for _, tt := range []struct {
+		method, target string
+		wantStatus     int
+		wantCode       string
+	}{
+		{http.MethodGet, "/api/itineraries/unknown", http.StatusNotFound, "itinerary_not_found"},
+		{http.MethodPost, "/api/itinerary?store=maybe", http.StatusBadRequest, "invalid_store"},
+	} {
+		rec := serve(tt.method, tt.target, `{"tickets":[["SFO","LAX"]]}`)
+		var problem models.Problem
+		_ = json.Unmarshal(rec.Body.Bytes(), &problem)
+		if rec.Code != tt.wantStatus || problem.Code != tt.wantCode {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.target, rec.Code, problem.Code, tt.wantStatus, tt.wantCode)
+		}
+	}
+
+	// Without store the itinerary is only returned
+	rec = serve(http.MethodPost, "/api/itinerary?store=false", `{"tickets":[["SFO","LAX"]]}`)
+	if rec.Code != http.StatusOK || rec.Header().Get(echo.HeaderLocation) != "" {
+		t.Errorf("store=false = %d, Location %q", rec.Code, rec.Header().Get(echo.HeaderLocation))
+	}
+}
+
+func TestPatchItinerary(t *testing.T) {
+	// Setup
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := services.NewItineraryService(ctx, cfg)
+	handler := NewItineraryHandler(service, services.NewItineraryStore(repository.NewMemoryRepository()))
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.POST("/api/itinerary", handler.ProcessItinerary)
+	e.GET("/api/itineraries/:id", handler.GetItinerary).Name = ItineraryRouteName
+	e.PATCH("/api/itineraries/:id", handler.PatchItinerary)
+	e.GET("/api/itineraries/:id/revisions", handler.GetRevisions)
+	e.GET("/api/itineraries/:id/revisions/:revision", handler.GetRevision)
+
+	serve := func(method, target, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if ifMatch != "" {
+			req.Header.Set(headerIfMatch, ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodPost, "/api/itinerary?store=true", "", `{"tickets":[["LAX","JFK"],["SFO","LAX"]]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("store status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if etag := rec.Header().Get(headerETag); etag != `"1"` {
+		t.Errorf("ETag = %q, want %q", etag, `"1"`)
+	}
+	location := rec.Header().Get(echo.HeaderLocation)
+
+	// Reroute the second flight through Boston
+	patch := `{"remove":[["LAX","JFK"]],"add":[["LAX","BOS"],["BOS","JFK"]]}`
+	rec = serve(http.MethodPatch, location, `"1"`, patch)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var patched models.StoredItinerary
+	if err := json.Unmarshal(rec.Body.Bytes(), &patched); err != nil {
+		t.Fatalf("Failed to unmarshal patched itinerary: %v", err)
+	}
+	if patched.Revision != 2 || !reflect.DeepEqual(patched.Itinerary, []string{"SFO", "LAX", "BOS", "JFK"}) {
+		t.Errorf("patched itinerary = %+v", patched)
+	}
+	if etag := rec.Header().Get(headerETag); etag != `"2"` {
+		t.Errorf("ETag = %q, want %q", etag, `"2"`)
+	}
+
+	rec = serve(http.MethodGet, location+"/revisions", "", "")
+	var revisions models.ItineraryRevisions
+	if err := json.Unmarshal(rec.Body.Bytes(), &revisions); err != nil || len(revisions.Revisions) != 2 {
+		t.Errorf("GET revisions = %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(http.MethodGet, location+"/revisions/1", "", "")
+	var first models.StoredItinerary
+	if err := json.Unmarshal(rec.Body.Bytes(), &first); err != nil || !reflect.DeepEqual(first.Itinerary, []string{"SFO", "LAX", "JFK"}) {
+		t.Errorf("GET revision 1 = %d %s", rec.Code, rec.Body.String())
+	}
+
+	for _, tt := range []struct {
+		name, method, target, ifMatch, body string
+		wantStatus                          int
+		wantCode                            string
+	}{
+		{"stale If-Match", http.MethodPatch, location, `"1"`, `{"add":[["JFK","MIA"]]}`, http.StatusPreconditionFailed, "precondition_failed"},
+		{"weak If-Match", http.MethodPatch, location, `W/"2"`, `{"add":[["JFK","MIA"]]}`, http.StatusPreconditionFailed, "precondition_failed"},
+		{"empty patch", http.MethodPatch, location, "", `{}`, http.StatusBadRequest, "empty_patch"},
+		{"malformed patch", http.MethodPatch, location, "", `{"add":`, http.StatusBadRequest, "invalid_format"},
+		{"unknown ticket", http.MethodPatch, location, "", `{"remove":[["LAX","JFK"]]}`, http.StatusConflict, "ticket_not_found"},
+		{"every ticket removed", http.MethodPatch, location, "*", `{"remove":[["SFO","LAX"],["LAX","BOS"],["BOS","JFK"]]}`, http.StatusBadRequest, "no_tickets_left"},
+		{"broken route", http.MethodPatch, location, "", `{"remove":[["LAX","BOS"]]}`, http.StatusBadRequest, "multiple_starts"},
+		{"unknown itinerary", http.MethodPatch, "/api/itineraries/unknown", "", `{"add":[["JFK","MIA"]]}`, http.StatusNotFound, "itinerary_not_found"},
+		{"unknown revision", http.MethodGet, location + "/revisions/3", "", "", http.StatusNotFound, "revision_not_found"},
+		{"invalid revision", http.MethodGet, location + "/revisions/first", "", "", http.StatusNotFound, "revision_not_found"},
+	} {
+		rec := serve(tt.method, tt.target, tt.ifMatch, tt.body)
+		var problem models.Problem
+		_ = json.Unmarshal(rec.Body.Bytes(), &problem)
+		if rec.Code != tt.wantStatus || problem.Code != tt.wantCode {
+			t.Errorf("%s: %s %s = %d %q, want %d %q", tt.name, tt.method, tt.target, rec.Code, problem.Code, tt.wantStatus, tt.wantCode)
+		}
+	}
+
+	// Failed patches leave the itinerary unchanged
+	rec = serve(http.MethodGet, location, "", "")
+	if etag := rec.Header().Get(headerETag); etag != `"2"` {
+		t.Errorf("ETag after failed patches = %q, want %q", etag, `"2"`)
+	}
+}
+
+func TestDiffItineraries(t *testing.T) {
+	// Setup
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := services.NewItineraryService(ctx, cfg)
+	handler := NewItineraryHandler(service, services.NewItineraryStore(repository.NewMemoryRepository()))
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.POST("/api/itinerary", handler.ProcessItinerary)
+	e.POST("/api/itineraries/diff", handler.DiffItineraries)
+	e.GET("/api/itineraries/:id", handler.GetItinerary).Name = ItineraryRouteName
+	e.PATCH("/api/itineraries/:id", handler.PatchItinerary)
+	e.GET("/api/itineraries/:id/diff", handler.DiffRevisions)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Two ticket sets
+	rec := serve(http.MethodPost, "/api/itineraries/diff", `{"from":{"tickets":[["LAX","JFK"],["SFO","LAX"]]},"to":{"tickets":[["DEN","JFK"],["SFO","DEN"]]}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST diff status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var diff models.ItineraryDiff
+	if err := json.Unmarshal(rec.Body.Bytes(), &diff); err != nil {
+		t.Fatalf("Failed to unmarshal diff: %v", err)
+	}
+	want := []models.Reroute{{From: []string{"SFO", "LAX", "JFK"}, To: []string{"SFO", "DEN", "JFK"}}}
+	if !reflect.DeepEqual(diff.Rerouted, want) || len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Errorf("POST diff = %+v", diff)
+	}
+
+	// Two revisions of a stored itinerary
+	rec = serve(http.MethodPost, "/api/itinerary?store=true", `{"tickets":[["SFO","LAX"]]}`)
+	location := rec.Header().Get(echo.HeaderLocation)
+	serve(http.MethodPatch, location, `{"add":[["LAX","JFK"]]}`)
+
+	rec = serve(http.MethodGet, location+"/diff", "")
+	diff = models.ItineraryDiff{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &diff); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET diff = %d %s", rec.Code, rec.Body.String())
+	}
+	if diff.FromRevision != 1 || diff.ToRevision != 2 || !reflect.DeepEqual(diff.Added, []models.TicketPair{{"LAX", "JFK"}}) {
+		t.Errorf("GET diff = %+v", diff)
+	}
+	if !reflect.DeepEqual(diff.Destination, &models.AirportChange{From: "LAX", To: "JFK"}) {
+		t.Errorf("GET diff destination = %+v", diff.Destination)
+	}
+
+	for _, tt := range []struct {
+		method, target, body string
+		wantStatus           int
+		wantCode             string
+	}{
+		{http.MethodPost, "/api/itineraries/diff", `{"from":`, http.StatusBadRequest, "invalid_format"},
+		{http.MethodPost, "/api/itineraries/diff", `{"from":{"tickets":[["SFO","LAX"]]}}`, http.StatusBadRequest, "no_tickets"},
+		{http.MethodPost, "/api/itineraries/diff", `{"from":{"tickets":[["SFO","LAX"]]},"to":{"tickets":[["SFO","LAX"],["JFK","LAX"]]}}`, http.StatusBadRequest, "multiple_starts"},
+		{http.MethodGet, location + "/diff?from=first", "", http.StatusBadRequest, "invalid_query_parameter"},
+		{http.MethodGet, location + "/diff?to=0", "", http.StatusBadRequest, "invalid_query_parameter"},
+		{http.MethodGet, location + "/diff?from=1&to=3", "", http.StatusNotFound, "revision_not_found"},
+		{http.MethodGet, "/api/itineraries/unknown/diff", "", http.StatusNotFound, "itinerary_not_found"},
+	} {
+		rec := serve(tt.method, tt.target, tt.body)
+		var problem models.Problem
+		_ = json.Unmarshal(rec.Body.Bytes(), &problem)
+		if rec.Code != tt.wantStatus || problem.Code != tt.wantCode {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.target, rec.Code, problem.Code, tt.wantStatus, tt.wantCode)
+		}
+	}
+}
+
+func TestListItineraries(t *testing.T) {
+	// Setup
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := services.NewItineraryService(ctx, cfg)
+	handler := NewItineraryHandler(service, services.NewItineraryStore(repository.NewMemoryRepository()))
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.POST("/api/itinerary", handler.ProcessItinerary)
+	e.GET("/api/itineraries", handler.ListItineraries)
+	e.GET("/api/itineraries/:id", handler.GetItinerary).Name = ItineraryRouteName
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, body := range []string{
+		`{"tickets":[["SFO","LAX"],["LAX","JFK"]],"passenger":"Ada","travel_date":"2026-10-20"}`,
+		`{"tickets":[["LAX","DXB"]],"passenger":"Grace","travel_date":"2026-10-21"}`,
+		`{"tickets":[["CDG","FCO"]],"passenger":"Ada","travel_date":"2026-10-22"}`,
+	} {
+		if rec := serve(http.MethodPost, "/api/itinerary?store=true", body); rec.Code != http.StatusCreated {
+			t.Fatalf("store status = %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	// list returns the passengers of the itineraries listed by target and the next cursor
+	list := func(target string) ([]string, string) {
+		rec := serve(http.MethodGet, target, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d: %s", target, rec.Code, rec.Body.String())
+		}
+		var response models.ItineraryList
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal list: %v", err)
+		}
+		var passengers []string
+		for _, itinerary := range response.Itineraries {
+			passengers = append(passengers, itinerary.Passenger+" "+itinerary.TravelDate)
+		}
+		return passengers, response.NextCursor
+	}
+
+	got, cursor := list("/api/itineraries?airport=LAX&limit=1")
+	if !reflect.DeepEqual(got, []string{"Ada 2026-10-20"}) || cursor == "" {
+		t.Fatalf("first page = %v, cursor %q", got, cursor)
+	}
+	if got, cursor = list("/api/itineraries?airport=LAX&limit=1&cursor=" + cursor); !reflect.DeepEqual(got, []string{"Grace 2026-10-21"}) || cursor != "" {
+		t.Errorf("second page = %v, cursor %q", got, cursor)
+	}
+	if got, _ = list("/api/itineraries?passenger=ada&travel_date_from=2026-10-21"); !reflect.DeepEqual(got, []string{"Ada 2026-10-22"}) {
+		t.Errorf("filtered list = %v", got)
+	}
+
+	for _, tt := range []struct {
+		target   string
+		wantCode string
+	}{
+		{"/api/itineraries?limit=ten", "invalid_query_parameter"},
+		{"/api/itineraries?limit=0", "invalid_limit"},
+		{"/api/itineraries?created_after=yesterday", "invalid_query_parameter"},
+		{"/api/itineraries?travel_date_to=2026-13-01", "invalid_travel_date"},
+		{"/api/itineraries?cursor=bogus", "invalid_cursor"},
+	} {
+		rec := serve(http.MethodGet, tt.target, "")
+		var problem models.Problem
+		_ = json.Unmarshal(rec.Body.Bytes(), &problem)
+		if rec.Code != http.StatusBadRequest || problem.Code != tt.wantCode {
+			t.Errorf("GET %s = %d %q, want 400 %q", tt.target, rec.Code, problem.Code, tt.wantCode)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"flight-itinerary-api/api/middleware"
+	"flight-itinerary-api/models"
+	"flight-itinerary-api/services"
+)
+
+// sseHeartbeatInterval is how often an idle event stream sends a comment to keep proxies from
+// closing the connection
+const sseHeartbeatInterval = 15 * time.Second
+
+// JobHandler handles HTTP requests for asynchronous itinerary jobs
+type JobHandler struct {
+	jobs *services.JobManager
+}
+
+// NewJobHandler creates a new instance of JobHandler
+func NewJobHandler(jobs *services.JobManager) *JobHandler {
+	return &JobHandler{
+		jobs: jobs,
+	}
+}
+
+// CreateJob handles the POST request that enqueues a reconstruction job and returns its ID
+func (h *JobHandler) CreateJob(c echo.Context) error {
+	var request models.JobRequest
+
+	// Parse request body
+	if err := c.Bind(&request); err != nil {
+		return models.ErrInvalidFormat
+	}
+
+	// Validate request
+	if err := request.Validate(batchLimit(c, h.jobs.MaxItems())); err != nil {
+		return err
+	}
+
+	// Every ticket set of a batch job counts against the rate limit
+	if err := middleware.ConsumeRateLimit(c, len(request.Requests)-1); err != nil {
+		return err
+	}
+
+	job, err := h.jobs.Submit(&request)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, strings.TrimSuffix(c.Request().URL.Path, "/")+"/"+job.ID)
+	return c.JSON(http.StatusAccepted, jobResponse(&job, requestLanguage(c)))
+}
+
+// GetJob handles the GET request reporting a job's status, progress and result
+func (h *JobHandler) GetJob(c echo.Context) error {
+	job, err := h.jobs.Get(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, jobResponse(&job, requestLanguage(c)))
+}
+
+// CancelJob handles the DELETE request cancelling a queued or running job
+func (h *JobHandler) CancelJob(c echo.Context) error {
+	job, err := h.jobs.Cancel(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, jobResponse(&job, requestLanguage(c)))
+}
+
+// GetDeliveries handles the GET request listing the callback delivery attempts of a job
+func (h *JobHandler) GetDeliveries(c echo.Context) error {
+	job, err := h.jobs.Deliveries(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	response := models.DeliveriesResponse{
+		JobID:       job.ID,
+		CallbackURL: job.CallbackURL,
+		Status:      string(job.DeliveryStatus),
+		Attempts:    make([]models.DeliveryAttempt, len(job.Deliveries)),
+	}
+	for i, attempt := range job.Deliveries {
+		response.Attempts[i] = models.DeliveryAttempt{
+			Attempt:    attempt.Attempt,
+			At:         attempt.At,
+			StatusCode: attempt.StatusCode,
+			Error:      string(attempt.Error),
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// StreamJobEvents handles the GET request streaming a job's progress as Server-Sent Events.
+// The stream starts with a "status" event carrying the current job state, continues with
+// "progress" events and ends with a "complete" event carrying the final job state.
+func (h *JobHandler) StreamJobEvents(c echo.Context) error {
+	id := c.Param("id")
+	job, events, unsubscribe, err := h.jobs.Subscribe(id)
+	if err != nil {
+		return err
+	}
+	defer unsubscribe()
+
+	language := requestLanguage(c)
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	// Disable response buffering in nginx style reverse proxies
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	if err := writeServerSentEvent(res, "status", jobResponse(&job, language)); err != nil {
+		return nil
+	}
+	res.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// The job is over; report its final state
+				if final, err := h.jobs.Get(id); err == nil {
+					_ = writeServerSentEvent(res, "complete", jobResponse(&final, language))
+					res.Flush()
+				}
+				return nil
+			}
+
+			err := writeServerSentEvent(res, "progress", models.JobEvent{
+				Item:  event.Item,
+				Stage: string(event.Stage),
+				Count: event.Count,
+				Progress: models.JobProgress{
+					Total:     event.Total,
+					Completed: event.Completed,
+				},
+			})
+			if err != nil {
+				return nil
+			}
+			res.Flush()
+
+		case <-heartbeat.C:
+			if _, err := io.WriteString(res, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+
+		case <-c.Request().Context().Done():
+			return nil
+		}
+	}
+}
+
+// jobResponse converts a job into its API representation with failures described in language
+func jobResponse(job *services.Job, language string) models.JobResponse {
+	return job.ResponseWith(func(err error) string {
+		return localize(language, err)
+	})
+}
+
+// writeServerSentEvent writes a single named event with JSON encoded data
+func writeServerSentEvent(w io.Writer, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
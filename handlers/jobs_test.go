@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/models"
+	"flight-itinerary-api/services"
+)
+
+func TestJobLifecycle(t *testing.T) {
+	// Setup
+	e := echo.New()
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 5,
+		},
+		Jobs: config.JobsConfig{
+			QueueSize: 4,
+			Workers:   1,
+			MaxItems:  10,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := services.NewItineraryService(ctx, cfg)
+	handler := NewJobHandler(services.NewJobManager(ctx, cfg, service))
+	e.HTTPErrorHandler = HTTPErrorHandler
+
+	e.POST("/api/jobs", handler.CreateJob)
+	e.GET("/api/jobs/:id", handler.GetJob)
+	e.DELETE("/api/jobs/:id", handler.CancelJob)
+
+	// serve runs a request through the router and decodes the job response
+	serve := func(method, target, body string) (*httptest.ResponseRecorder, models.JobResponse) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var response models.JobResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec, response
+	}
+
+	// Invalid submissions are rejected synchronously
+	rec, _ := serve(http.MethodPost, "/api/jobs", `{"tickets":[]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("CreateJob() status = %v, want %v", rec.Code, http.StatusBadRequest)
+	}
+
+	rec, created := serve(http.MethodPost, "/api/jobs", `{"requests":[{"tickets":[["LAX","JFK"],["SFO","LAX"]]},{"tickets":[["SFO"]]}]}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("CreateJob() status = %v, want %v", rec.Code, http.StatusAccepted)
+	}
+	if created.ID == "" {
+		t.Fatal("CreateJob() returned no job ID")
+	}
+	if got := rec.Header().Get(echo.HeaderLocation); got != "/api/jobs/"+created.ID {
+		t.Errorf("CreateJob() Location = %q", got)
+	}
+
+	// Poll until the job completes
+	var job models.JobResponse
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		rec, job = serve(http.MethodGet, "/api/jobs/"+created.ID, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GetJob() status = %v, want %v", rec.Code, http.StatusOK)
+		}
+		if job.Status != string(services.JobQueued) && job.Status != string(services.JobRunning) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if job.Status != string(services.JobCompleted) {
+		t.Fatalf("job status = %v, want %v", job.Status, services.JobCompleted)
+	}
+	wantResults := []models.BatchItemResult{
+		{Itinerary: []string{"SFO", "LAX", "JFK"}},
+		{Error: "invalid ticket format: each ticket must have exactly source and destination", Code: "invalid_ticket_format"},
+	}
+	if !reflect.DeepEqual(job.Results, wantResults) {
+		t.Errorf("job results = %+v, want %+v", job.Results, wantResults)
+	}
+	if job.Progress.Completed != 2 || job.Progress.Total != 2 {
+		t.Errorf("job progress = %+v, want 2/2", job.Progress)
+	}
+
+	// Finished jobs cannot be cancelled, unknown jobs are not found
+	if rec, _ := serve(http.MethodDelete, "/api/jobs/"+created.ID, ""); rec.Code != http.StatusConflict {
+		t.Errorf("CancelJob() status = %v, want %v", rec.Code, http.StatusConflict)
+	}
+	if rec, _ := serve(http.MethodGet, "/api/jobs/unknown", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GetJob() status = %v, want %v", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestStreamJobEvents(t *testing.T) {
+	// Setup without job workers, so the job stays queued until cancelled
+	e := echo.New()
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+		Jobs: config.JobsConfig{
+			QueueSize: 1,
+			MaxItems:  10,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	jobs := services.NewJobManager(ctx, cfg, services.NewItineraryService(ctx, cfg))
+	handler := NewJobHandler(jobs)
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.GET("/api/jobs/:id/events", handler.StreamJobEvents)
+
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	job, err := jobs.Submit(&models.JobRequest{Tickets: []models.TicketPair{{"SFO", "JFK"}}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	resp, err := http.Get(server.URL + "/api/jobs/" + job.ID + "/events")
+	if err != nil {
+		t.Fatalf("GET events error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get(echo.HeaderContentType); got != "text/event-stream" {
+		t.Errorf("content type = %q, want text/event-stream", got)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	// readEvent reads the next event name and data
+	readEvent := func() (string, models.JobResponse) {
+		var (
+			name     string
+			response models.JobResponse
+		)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading event stream: %v", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				_ = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &response)
+			case line == "" && name != "":
+				return name, response
+			}
+		}
+	}
+
+	name, state := readEvent()
+	if name != "status" || state.Status != string(services.JobQueued) {
+		t.Errorf("first event = %q with status %q, want status/queued", name, state.Status)
+	}
+
+	if _, err := jobs.Cancel(job.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+
+	name, state = readEvent()
+	if name != "complete" || state.Status != string(services.JobCancelled) {
+		t.Errorf("last event = %q with status %q, want complete/cancelled", name, state.Status)
+	}
+
+	// Unknown jobs are not found
+	resp, err = http.Get(server.URL + "/api/jobs/unknown/events")
+	if err != nil {
+		t.Fatalf("GET events error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown job status = %v, want %v", resp.StatusCode, http.StatusNotFound)
+	}
+}
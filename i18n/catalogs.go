@@ -0,0 +1,234 @@
+package i18n
+
+// english is the reference catalog; every other catalog translates its codes
+var english = map[string]string{
+	// Request validation
+	"invalid_format":        "invalid request format",
+	"no_tickets":            "no tickets provided",
+	"invalid_ticket_format": "invalid ticket format: each ticket must have exactly source and destination",
+	"empty_airport_code":    "invalid ticket: airport codes cannot be empty",
+	"invalid_airport_code":  "invalid airport code: must be 3 characters",
+	"no_ticket_sets":        "no ticket sets provided",
+	"too_many_ticket_sets":  "too many ticket sets: maximum is %d",
+	"line_too_long":         "ticket set on line %d exceeds %d bytes",
+	"ambiguous_job":         "provide either tickets or requests, not both",
+	"invalid_callback_url":  "invalid callback URL: must be an absolute http or https URL",
+
+	// Route reconstruction
+	"duplicate_source":   "invalid tickets: multiple flights from same source",
+	"multiple_starts":    "invalid tickets: multiple starting points found",
+	"no_start":           "invalid tickets: no starting point found",
+	"disconnected_route": "invalid tickets: disconnected route",
+
+	// Jobs
+	"job_queue_full":     "job queue is full",
+	"job_not_found":      "job not found",
+	"job_finished":       "job already finished",
+	"no_callback":        "job has no callback URL",
+	"callbacks_disabled": "callbacks are not enabled on this server",
+	"callback_forbidden": "invalid callback URL: must not point to a loopback, private or link-local address",
+
+	// Service state
+	"overloaded":         "service overloaded, retry later",
+	"shutting_down":      "service is shutting down",
+	"processing_timeout": "processing deadline exceeded",
+	"request_cancelled":  "request cancelled",
+	"internal_error":     "internal error",
+
+	// HTTP
+	"not_found":                "resource not found",
+	"method_not_allowed":       "method not allowed",
+	"unsupported_media_type":   "unsupported media type: send application/json",
+	"too_many_requests":        "too many requests",
+	"request_entity_too_large": "request body too large",
+
+	// Idempotency keys
+	"invalid_idempotency_key":    "invalid Idempotency-Key: must be at most 255 characters",
+	"idempotency_key_in_use":     "a request with this Idempotency-Key is still being processed",
+	"idempotency_key_reused":     "Idempotency-Key was already used with a different request body",
+	"idempotency_keys_exhausted": "too many Idempotency-Keys are in use, retry later",
+
+	// Stored itineraries
+	"invalid_store":           "invalid store parameter: must be true or false",
+	"itinerary_not_found":     "itinerary not found",
+	"invalid_passenger":       "invalid passenger: must be at most %d characters",
+	"invalid_travel_date":     "invalid travel date: must be formatted as YYYY-MM-DD",
+	"invalid_limit":           "invalid limit: must be between 1 and %d",
+	"invalid_query_parameter": "invalid query parameter: %s",
+	"invalid_cursor":          "invalid cursor: pass the next_cursor of a previous page",
+
+	// Itinerary edits
+	"empty_patch":         "empty patch: add, remove or replace at least one ticket",
+	"ticket_not_found":    "ticket to remove or replace is not part of the itinerary",
+	"no_tickets_left":     "the patch would remove every ticket of the itinerary",
+	"revision_not_found":  "itinerary revision not found",
+	"precondition_failed": "the itinerary was modified: its revision does not match If-Match",
+}
+
+var french = map[string]string{
+	"invalid_format":        "format de requête invalide",
+	"no_tickets":            "aucun billet fourni",
+	"invalid_ticket_format": "format de billet invalide : chaque billet doit comporter exactement une origine et une destination",
+	"empty_airport_code":    "billet invalide : les codes d'aéroport ne peuvent pas être vides",
+	"invalid_airport_code":  "code d'aéroport invalide : il doit comporter 3 caractères",
+	"no_ticket_sets":        "aucun ensemble de billets fourni",
+	"too_many_ticket_sets":  "trop d'ensembles de billets : le maximum est de %d",
+	"line_too_long":         "l'ensemble de billets de la ligne %d dépasse %d octets",
+	"ambiguous_job":         "fournissez soit des billets, soit des requêtes, mais pas les deux",
+	"invalid_callback_url":  "URL de rappel invalide : elle doit être une URL http ou https absolue",
+
+	"duplicate_source":   "billets invalides : plusieurs vols depuis la même origine",
+	"multiple_starts":    "billets invalides : plusieurs points de départ trouvés",
+	"no_start":           "billets invalides : aucun point de départ trouvé",
+	"disconnected_route": "billets invalides : itinéraire discontinu",
+
+	"job_queue_full":     "la file des tâches est pleine",
+	"job_not_found":      "tâche introuvable",
+	"job_finished":       "la tâche est déjà terminée",
+	"no_callback":        "la tâche n'a pas d'URL de rappel",
+	"callbacks_disabled": "les rappels ne sont pas activés sur ce serveur",
+	"callback_forbidden": "URL de rappel invalide : elle ne doit pas désigner une adresse de bouclage, privée ou lien-local",
+
+	"overloaded":         "service surchargé, réessayez plus tard",
+	"shutting_down":      "le service est en cours d'arrêt",
+	"processing_timeout": "délai de traitement dépassé",
+	"request_cancelled":  "requête annulée",
+	"internal_error":     "erreur interne",
+
+	"not_found":                "ressource introuvable",
+	"method_not_allowed":       "méthode non autorisée",
+	"unsupported_media_type":   "type de média non pris en charge : envoyez application/json",
+	"too_many_requests":        "trop de requêtes",
+	"request_entity_too_large": "corps de requête trop volumineux",
+
+	"invalid_idempotency_key":    "Idempotency-Key invalide : 255 caractères au maximum",
+	"idempotency_key_in_use":     "une requête avec cette Idempotency-Key est encore en cours de traitement",
+	"idempotency_key_reused":     "cette Idempotency-Key a déjà été utilisée avec un autre corps de requête",
+	"idempotency_keys_exhausted": "trop d'Idempotency-Keys sont en cours d'utilisation, réessayez plus tard",
+
+	"invalid_store":           "paramètre store invalide : doit valoir true ou false",
+	"itinerary_not_found":     "itinéraire introuvable",
+	"invalid_passenger":       "passager invalide : %d caractères au maximum",
+	"invalid_travel_date":     "date de voyage invalide : elle doit être au format AAAA-MM-JJ",
+	"invalid_limit":           "limite invalide : elle doit être comprise entre 1 et %d",
+	"invalid_query_parameter": "paramètre de requête invalide : %s",
+	"invalid_cursor":          "curseur invalide : transmettez le next_cursor d'une page précédente",
+
+	"empty_patch":         "correctif vide : ajoutez, supprimez ou remplacez au moins un billet",
+	"ticket_not_found":    "le billet à supprimer ou à remplacer ne fait pas partie de l'itinéraire",
+	"no_tickets_left":     "le correctif supprimerait tous les billets de l'itinéraire",
+	"revision_not_found":  "révision de l'itinéraire introuvable",
+	"precondition_failed": "l'itinéraire a été modifié : sa révision ne correspond pas à If-Match",
+}
+
+var arabic = map[string]string{
+	"invalid_format":        "تنسيق الطلب غير صالح",
+	"no_tickets":            "لم يتم تقديم أي تذاكر",
+	"invalid_ticket_format": "تنسيق التذكرة غير صالح: يجب أن تحتوي كل تذكرة على مصدر ووجهة فقط",
+	"empty_airport_code":    "تذكرة غير صالحة: لا يمكن أن تكون رموز المطارات فارغة",
+	"invalid_airport_code":  "رمز المطار غير صالح: يجب أن يتكون من 3 أحرف",
+	"no_ticket_sets":        "لم يتم تقديم أي مجموعات تذاكر",
+	"too_many_ticket_sets":  "عدد مجموعات التذاكر كبير جدًا: الحد الأقصى هو %d",
+	"line_too_long":         "مجموعة التذاكر في السطر %d تتجاوز %d بايت",
+	"ambiguous_job":         "قدّم إما تذاكر أو طلبات، وليس كليهما",
+	"invalid_callback_url":  "عنوان رد الاتصال غير صالح: يجب أن يكون عنوان http أو https مطلقًا",
+
+	"duplicate_source":   "تذاكر غير صالحة: عدة رحلات من المصدر نفسه",
+	"multiple_starts":    "تذاكر غير صالحة: تم العثور على عدة نقاط انطلاق",
+	"no_start":           "تذاكر غير صالحة: لم يتم العثور على نقطة انطلاق",
+	"disconnected_route": "تذاكر غير صالحة: المسار غير متصل",
+
+	"job_queue_full":     "قائمة انتظار المهام ممتلئة",
+	"job_not_found":      "المهمة غير موجودة",
+	"job_finished":       "المهمة انتهت بالفعل",
+	"no_callback":        "لا تحتوي المهمة على عنوان رد اتصال",
+	"callbacks_disabled": "ردود الاتصال غير مفعّلة على هذا الخادم",
+	"callback_forbidden": "عنوان URL لرد الاتصال غير صالح: يجب ألا يشير إلى عنوان استرجاع أو خاص أو محلي للرابط",
+
+	"overloaded":         "الخدمة مثقلة، أعد المحاولة لاحقًا",
+	"shutting_down":      "الخدمة قيد الإيقاف",
+	"processing_timeout": "تم تجاوز مهلة المعالجة",
+	"request_cancelled":  "تم إلغاء الطلب",
+	"internal_error":     "خطأ داخلي",
+
+	"not_found":                "المورد غير موجود",
+	"method_not_allowed":       "الطريقة غير مسموح بها",
+	"unsupported_media_type":   "نوع الوسائط غير مدعوم: أرسل application/json",
+	"too_many_requests":        "طلبات كثيرة جدًا",
+	"request_entity_too_large": "نص الطلب كبير جدًا",
+
+	"invalid_idempotency_key":    "قيمة Idempotency-Key غير صالحة: 255 حرفًا كحد أقصى",
+	"idempotency_key_in_use":     "لا يزال طلب بنفس Idempotency-Key قيد المعالجة",
+	"idempotency_key_reused":     "سبق استخدام Idempotency-Key مع نص طلب مختلف",
+	"idempotency_keys_exhausted": "عدد كبير جدًا من مفاتيح Idempotency-Key قيد الاستخدام، أعد المحاولة لاحقًا",
+
+	"invalid_store":           "قيمة المعامل store غير صالحة: يجب أن تكون true أو false",
+	"itinerary_not_found":     "خط الرحلة غير موجود",
+	"invalid_passenger":       "اسم المسافر غير صالح: %d حرفًا كحد أقصى",
+	"invalid_travel_date":     "تاريخ السفر غير صالح: يجب أن يكون بالتنسيق YYYY-MM-DD",
+	"invalid_limit":           "الحد غير صالح: يجب أن يكون بين 1 و %d",
+	"invalid_query_parameter": "معامل استعلام غير صالح: %s",
+	"invalid_cursor":          "مؤشر غير صالح: استخدم قيمة next_cursor من صفحة سابقة",
+
+	"empty_patch":         "تعديل فارغ: أضف تذكرة واحدة على الأقل أو احذفها أو استبدلها",
+	"ticket_not_found":    "التذكرة المراد حذفها أو استبدالها ليست جزءًا من خط الرحلة",
+	"no_tickets_left":     "سيؤدي التعديل إلى حذف جميع تذاكر خط الرحلة",
+	"revision_not_found":  "مراجعة خط الرحلة غير موجودة",
+	"precondition_failed": "تم تعديل خط الرحلة: مراجعته لا تطابق If-Match",
+}
+
+var spanish = map[string]string{
+	"invalid_format":        "formato de solicitud no válido",
+	"no_tickets":            "no se proporcionaron billetes",
+	"invalid_ticket_format": "formato de billete no válido: cada billete debe tener exactamente origen y destino",
+	"empty_airport_code":    "billete no válido: los códigos de aeropuerto no pueden estar vacíos",
+	"invalid_airport_code":  "código de aeropuerto no válido: debe tener 3 caracteres",
+	"no_ticket_sets":        "no se proporcionaron conjuntos de billetes",
+	"too_many_ticket_sets":  "demasiados conjuntos de billetes: el máximo es %d",
+	"line_too_long":         "el conjunto de billetes de la línea %d supera los %d bytes",
+	"ambiguous_job":         "proporcione billetes o solicitudes, pero no ambos",
+	"invalid_callback_url":  "URL de retorno no válida: debe ser una URL http o https absoluta",
+
+	"duplicate_source":   "billetes no válidos: varios vuelos desde el mismo origen",
+	"multiple_starts":    "billetes no válidos: se encontraron varios puntos de partida",
+	"no_start":           "billetes no válidos: no se encontró ningún punto de partida",
+	"disconnected_route": "billetes no válidos: ruta desconectada",
+
+	"job_queue_full":     "la cola de trabajos está llena",
+	"job_not_found":      "trabajo no encontrado",
+	"job_finished":       "el trabajo ya ha terminado",
+	"no_callback":        "el trabajo no tiene URL de retorno",
+	"callbacks_disabled": "las llamadas de retorno no están habilitadas en este servidor",
+	"callback_forbidden": "URL de retorno no válida: no debe apuntar a una dirección de bucle invertido, privada o de enlace local",
+
+	"overloaded":         "servicio sobrecargado, inténtelo de nuevo más tarde",
+	"shutting_down":      "el servicio se está deteniendo",
+	"processing_timeout": "se superó el plazo de procesamiento",
+	"request_cancelled":  "solicitud cancelada",
+	"internal_error":     "error interno",
+
+	"not_found":                "recurso no encontrado",
+	"method_not_allowed":       "método no permitido",
+	"unsupported_media_type":   "tipo de medio no admitido: envíe application/json",
+	"too_many_requests":        "demasiadas solicitudes",
+	"request_entity_too_large": "cuerpo de solicitud demasiado grande",
+
+	"invalid_idempotency_key":    "Idempotency-Key no válida: como máximo 255 caracteres",
+	"idempotency_key_in_use":     "una solicitud con esta Idempotency-Key aún se está procesando",
+	"idempotency_key_reused":     "esta Idempotency-Key ya se usó con otro cuerpo de solicitud",
+	"idempotency_keys_exhausted": "hay demasiadas Idempotency-Key en uso, reintente más tarde",
+
+	"invalid_store":           "parámetro store no válido: debe ser true o false",
+	"itinerary_not_found":     "itinerario no encontrado",
+	"invalid_passenger":       "pasajero no válido: como máximo %d caracteres",
+	"invalid_travel_date":     "fecha de viaje no válida: debe tener el formato AAAA-MM-DD",
+	"invalid_limit":           "límite no válido: debe estar entre 1 y %d",
+	"invalid_query_parameter": "parámetro de consulta no válido: %s",
+	"invalid_cursor":          "cursor no válido: envíe el next_cursor de una página anterior",
+
+	"empty_patch":         "parche vacío: añada, elimine o reemplace al menos un billete",
+	"ticket_not_found":    "el billete a eliminar o reemplazar no forma parte del itinerario",
+	"no_tickets_left":     "el parche eliminaría todos los billetes del itinerario",
+	"revision_not_found":  "revisión del itinerario no encontrada",
+	"precondition_failed": "el itinerario fue modificado: su revisión no coincide con If-Match",
+}
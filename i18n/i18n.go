@@ -0,0 +1,78 @@
+// Package i18n renders the messages of error codes in the languages supported by the API
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is used when a client accepts none of the supported languages
+const DefaultLanguage = "en"
+
+// catalogs maps every supported language to its messages, keyed by error code. Messages
+// taking arguments use fmt verbs in the same order in every language.
+var catalogs = map[string]map[string]string{
+	"en": english,
+	"fr": french,
+	"ar": arabic,
+	"es": spanish,
+}
+
+// Supported returns the supported languages, sorted
+func Supported() []string {
+	languages := make([]string, 0, len(catalogs))
+	for language := range catalogs {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Negotiate returns the supported language preferred by an Accept-Language header value,
+// matching on the primary subtag so that "fr-CA" selects French. Languages are ranked by
+// quality and then by their order in the header, and DefaultLanguage is returned when none
+// of them is supported.
+func Negotiate(acceptLanguage string) string {
+	best, bestQuality := DefaultLanguage, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = q
+		}
+		if quality <= bestQuality {
+			continue
+		}
+
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if primary == "*" {
+			primary = DefaultLanguage
+		}
+		if _, supported := catalogs[primary]; supported {
+			best, bestQuality = primary, quality
+		}
+	}
+	return best
+}
+
+// Message renders the message of code in language, falling back to English when the language
+// or the code is missing from its catalog. It reports false when no catalog knows the code.
+func Message(language, code string, args ...interface{}) (string, bool) {
+	format, found := catalogs[language][code]
+	if !found {
+		format, found = catalogs[DefaultLanguage][code]
+	}
+	if !found {
+		return "", false
+	}
+	if len(args) == 0 {
+		return format, true
+	}
+	return fmt.Sprintf(format, args...), true
+}
@@ -0,0 +1,68 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{"empty header", "", "en"},
+		{"exact match", "fr", "fr"},
+		{"region subtag", "es-MX", "es"},
+		{"case insensitive", "AR-sa", "ar"},
+		{"highest quality wins", "fr;q=0.5, ar;q=0.8", "ar"},
+		{"header order breaks ties", "es, fr", "es"},
+		{"unsupported languages skipped", "de-DE, ja;q=0.9, fr;q=0.1", "fr"},
+		{"nothing supported", "de, ja", "en"},
+		{"wildcard", "*", "en"},
+		{"refused language", "fr;q=0, es;q=0.2", "es"},
+		{"malformed quality ignored", "fr;q=high, es", "es"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Negotiate(tt.acceptLanguage); got != tt.want {
+				t.Errorf("Negotiate(%q) = %q, want %q", tt.acceptLanguage, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessage(t *testing.T) {
+	if got, _ := Message("fr", "too_many_ticket_sets", 5); got != "trop d'ensembles de billets : le maximum est de 5" {
+		t.Errorf("Message() = %q", got)
+	}
+	if got, _ := Message("de", "no_tickets"); got != "no tickets provided" {
+		t.Errorf("Message() with unsupported language = %q, want english", got)
+	}
+	if _, found := Message("en", "unknown_code"); found {
+		t.Error("Message() found an unknown code")
+	}
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	for _, language := range Supported() {
+		catalog := catalogs[language]
+		for code, format := range english {
+			translated, found := catalog[code]
+			if !found {
+				t.Errorf("%s catalog is missing %q", language, code)
+				continue
+			}
+			// Arguments are formatted positionally, so every translation needs the same verbs
+			if strings.Count(translated, "%d") != strings.Count(format, "%d") {
+				t.Errorf("%s message of %q has different arguments than english", language, code)
+			}
+		}
+		for code := range catalog {
+			if _, found := english[code]; !found {
+				t.Errorf("%s catalog has %q, which english lacks", language, code)
+			}
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"flight-itinerary-api/api"
 	"flight-itinerary-api/config"
+	"flight-itinerary-api/repository"
 	"flight-itinerary-api/services"
 )
 
@@ -38,11 +39,19 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Open the repository of stored itineraries
+	repo, err := repository.New(&cfg.Storage)
+	if err != nil {
+		l.Fatal("Failed to open the itinerary repository", zap.Error(err))
+	}
+
 	// Initialize services with configured worker count
-	itineraryService := services.NewItineraryService(ctx, cfg)
+	itineraryService := services.NewItineraryService(ctx, cfg, services.WithLogger(l))
+	itineraryStore := services.NewItineraryStore(repo)
+	jobManager := services.NewJobManager(ctx, cfg, itineraryService)
 
 	// Setup router
-	router := api.NewRouter(ctx, cfg, l, itineraryService)
+	router := api.NewRouter(ctx, cfg, l, itineraryService, itineraryStore, jobManager)
 	router.SetupRoutes(e)
 
 	// Start server in a goroutine
@@ -69,6 +78,11 @@ func main() {
 		l.Info("Server shut down gracefully")
 	}
 
+	// Close the repository once no request can write to it
+	if err := repo.Close(); err != nil {
+		l.Error("Failed to close the itinerary repository", zap.Error(err))
+	}
+
 	// Final cleanup
 	if err := l.Sync(); err != nil {
 		log.Printf("Failed to sync logger: %v", err)
@@ -0,0 +1,216 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// contentType is the media type of the Prometheus text exposition format
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// labelEscaper escapes label values as required by the text format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Metric kinds
+const (
+	kindCounter = "counter"
+	kindGauge   = "gauge"
+)
+
+// Registry holds the application's metrics and serves them in the Prometheus text format.
+// Metrics are created on first use and live as long as the registry.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// family groups the series of one metric name
+type family struct {
+	name   string
+	help   string
+	kind   string
+	series map[string]*Value
+}
+
+// Value is a single float64 series that can be updated concurrently
+type Value struct {
+	bits atomic.Uint64
+}
+
+// Counter is a value that only goes up
+type Counter struct {
+	value *Value
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	value *Value
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		families: make(map[string]*family),
+	}
+}
+
+// Counter returns the counter with the given name and label pairs, creating it if needed.
+// Labels are given as alternating names and values.
+func (r *Registry) Counter(name, help string, labels ...string) Counter {
+	return Counter{value: r.value(name, help, kindCounter, labels)}
+}
+
+// Gauge returns the gauge with the given name and label pairs, creating it if needed.
+// Labels are given as alternating names and values.
+func (r *Registry) Gauge(name, help string, labels ...string) Gauge {
+	return Gauge{value: r.value(name, help, kindGauge, labels)}
+}
+
+// value looks up or creates a series
+func (r *Registry) value(name, help, kind string, labels []string) *Value {
+	if len(labels)%2 != 0 {
+		panic(fmt.Sprintf("metrics: odd number of label arguments for %s", name))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, exists := r.families[name]
+	if !exists {
+		f = &family{name: name, help: help, kind: kind, series: make(map[string]*Value)}
+		r.families[name] = f
+	} else if f.kind != kind {
+		panic(fmt.Sprintf("metrics: %s registered as %s, requested as %s", name, f.kind, kind))
+	}
+
+	key := formatLabels(labels)
+	v, exists := f.series[key]
+	if !exists {
+		v = &Value{}
+		f.series[key] = v
+	}
+	return v
+}
+
+// ServeHTTP writes every metric in the Prometheus text format, sorted by name and labels
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+
+	out := bufio.NewWriter(w)
+	for _, f := range r.snapshot() {
+		fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(out, "%s%s %s\n", f.name, key, strconv.FormatFloat(f.series[key].Load(), 'g', -1, 64))
+		}
+	}
+	_ = out.Flush()
+}
+
+// snapshot copies the families and their series, sorted by name, so they can be rendered
+// without holding the lock
+func (r *Registry) snapshot() []family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	families := make([]family, 0, len(r.families))
+	for _, f := range r.families {
+		copied := *f
+		copied.series = make(map[string]*Value, len(f.series))
+		for key, v := range f.series {
+			copied.series[key] = v
+		}
+		families = append(families, copied)
+	}
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].name < families[j].name
+	})
+	return families
+}
+
+// formatLabels renders label pairs as {name="value",...}, or nothing without labels
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(labels[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// Load returns the current value
+func (v *Value) Load() float64 {
+	return math.Float64frombits(v.bits.Load())
+}
+
+// add adds delta to the value
+func (v *Value) add(delta float64) {
+	for {
+		old := v.bits.Load()
+		updated := math.Float64bits(math.Float64frombits(old) + delta)
+		if v.bits.CompareAndSwap(old, updated) {
+			return
+		}
+	}
+}
+
+// Inc increments the counter by one
+func (c Counter) Inc() {
+	c.value.add(1)
+}
+
+// Add increases the counter by delta, which must not be negative
+func (c Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	c.value.add(delta)
+}
+
+// Value returns the current count
+func (c Counter) Value() float64 {
+	return c.value.Load()
+}
+
+// Set sets the gauge to value
+func (g Gauge) Set(value float64) {
+	g.value.bits.Store(math.Float64bits(value))
+}
+
+// Inc increments the gauge by one
+func (g Gauge) Inc() {
+	g.value.add(1)
+}
+
+// Dec decrements the gauge by one
+func (g Gauge) Dec() {
+	g.value.add(-1)
+}
+
+// Value returns the current value of the gauge
+func (g Gauge) Value() float64 {
+	return g.value.Load()
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+
+	registry.Counter("requests_total", "Requests served.", "priority", "bulk").Add(2)
+	registry.Counter("requests_total", "Requests served.", "priority", "interactive").Inc()
+	registry.Counter("requests_total", "Requests served.", "priority", "interactive").Inc()
+	gauge := registry.Gauge("in_flight", "Requests in flight.")
+	gauge.Inc()
+	gauge.Inc()
+	gauge.Dec()
+	registry.Gauge("label_escaping", "Escaped label values.", "path", `a"b\c`).Set(0.5)
+
+	// Counters never go down
+	registry.Counter("requests_total", "Requests served.", "priority", "bulk").Add(-1)
+
+	rec := httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	want := `# HELP in_flight Requests in flight.
+# TYPE in_flight gauge
+in_flight 1
+# HELP label_escaping Escaped label values.
+# TYPE label_escaping gauge
+label_escaping{path="a\"b\\c"} 0.5
+# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{priority="bulk"} 2
+requests_total{priority="interactive"} 2
+`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("ServeHTTP() body =\n%s\nwant\n%s", got, want)
+	}
+	if got := rec.Header().Get("Content-Type"); got != contentType {
+		t.Errorf("Content-Type = %q, want %q", got, contentType)
+	}
+}
+
+func TestRegistryKindMismatch(t *testing.T) {
+	registry := NewRegistry()
+	registry.Counter("value", "A value.")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Gauge() with a counter's name did not panic")
+		}
+	}()
+	registry.Gauge("value", "A value.")
+}
@@ -0,0 +1,50 @@
+package models
+
+// BatchItineraryRequest represents a request containing several independent ticket sets
+type BatchItineraryRequest struct {
+	Requests []ItineraryRequest `json:"requests"`
+}
+
+// BatchItemResult represents the outcome of a single ticket set within a batch
+type BatchItemResult struct {
+	Itinerary []string `json:"itinerary,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	Code      string   `json:"code,omitempty"`
+}
+
+// BatchItineraryResponse represents the API response for a batch, in input order
+type BatchItineraryResponse struct {
+	Results []BatchItemResult `json:"results"`
+}
+
+// errNoTicketSets is returned for batches and batch jobs without any ticket set
+var errNoTicketSets = newValidationError("no_ticket_sets", "no ticket sets provided")
+
+// tooManyTicketSets reports a batch exceeding the maximum number of ticket sets
+func tooManyTicketSets(maxItems int) error {
+	return newValidationErrorf("too_many_ticket_sets", "too many ticket sets: maximum is %d", maxItems)
+}
+
+// Validate checks the batch envelope; individual ticket sets are validated separately
+// so that one invalid item does not reject the whole batch
+func (r *BatchItineraryRequest) Validate(maxItems int) error {
+	if len(r.Requests) == 0 {
+		return errNoTicketSets
+	}
+	if maxItems > 0 && len(r.Requests) > maxItems {
+		return tooManyTicketSets(maxItems)
+	}
+	return nil
+}
+
+// StreamLineTooLong reports a line of an NDJSON stream exceeding the maximum line size
+func StreamLineTooLong(line, maxBytes int) error {
+	return newValidationErrorf("line_too_long", "ticket set on line %d exceeds %d bytes", line, maxBytes)
+}
+
+// StreamItemResult represents a single result line of an NDJSON stream, tagged with the
+// zero-based position of its ticket set in the input
+type StreamItemResult struct {
+	Index int `json:"index"`
+	BatchItemResult
+}
@@ -0,0 +1,51 @@
+package models
+
+import "testing"
+
+func TestBatchItineraryRequestValidate(t *testing.T) {
+	validSet := ItineraryRequest{Tickets: []TicketPair{{"SFO", "LAX"}}}
+
+	tests := []struct {
+		name     string
+		request  BatchItineraryRequest
+		maxItems int
+		wantErr  bool
+	}{
+		{
+			name:     "empty batch",
+			request:  BatchItineraryRequest{},
+			maxItems: 10,
+			wantErr:  true,
+		},
+		{
+			name:     "too many ticket sets",
+			request:  BatchItineraryRequest{Requests: []ItineraryRequest{validSet, validSet, validSet}},
+			maxItems: 2,
+			wantErr:  true,
+		},
+		{
+			name: "invalid items do not fail the envelope",
+			request: BatchItineraryRequest{Requests: []ItineraryRequest{
+				validSet,
+				{Tickets: []TicketPair{{"SFO"}}},
+			}},
+			maxItems: 2,
+			wantErr:  false,
+		},
+		{
+			name:     "unlimited batch size",
+			request:  BatchItineraryRequest{Requests: []ItineraryRequest{validSet, validSet, validSet}},
+			maxItems: 0,
+			wantErr:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.request.Validate(tt.maxItems)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("BatchItineraryRequest.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrInvalidFormat is returned when a request body is not well-formed JSON of the expected shape
+var ErrInvalidFormat = newValidationError("invalid_format", "invalid request format")
+
+// DecodeItineraryRequest reads an itinerary request from r token by token. Each ticket is
+// validated and passed to visit as soon as it has been read, so the ticket list is never
+// materialised; every other field is decoded into request, whose Tickets stay nil.
+// Decoding stops at the first error returned by visit.
+func DecodeItineraryRequest(r io.Reader, request *ItineraryRequest, visit func(src, dst string) error) error {
+	dec := json.NewDecoder(r)
+
+	token, err := dec.Token()
+	if errors.Is(err, io.EOF) {
+		// An empty body is an empty request
+		return errNoTickets
+	}
+	if delim, ok := token.(json.Delim); err != nil || !ok || delim != '{' {
+		return ErrInvalidFormat
+	}
+
+	tickets := 0
+	others := make(map[string]json.RawMessage)
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return ErrInvalidFormat
+		}
+		key, _ := token.(string)
+
+		if !strings.EqualFold(key, "tickets") {
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return ErrInvalidFormat
+			}
+			others[key] = value
+			continue
+		}
+
+		if tickets, err = decodeTickets(dec, visit); err != nil {
+			return err
+		}
+	}
+
+	if err := expectDelim(dec, '}'); err != nil {
+		return err
+	}
+	if tickets == 0 {
+		return errNoTickets
+	}
+
+	if len(others) == 0 {
+		return nil
+	}
+
+	// Decode the remaining fields with the regular struct tags
+	rest, err := json.Marshal(others)
+	if err != nil {
+		return ErrInvalidFormat
+	}
+	if err := json.Unmarshal(rest, request); err != nil {
+		return ErrInvalidFormat
+	}
+	return nil
+}
+
+// decodeTickets streams the tickets array, validating every ticket, and returns the number of tickets read
+func decodeTickets(dec *json.Decoder, visit func(src, dst string) error) (int, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return 0, ErrInvalidFormat
+	}
+	if token == nil {
+		return 0, nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return 0, ErrInvalidFormat
+	}
+
+	count := 0
+	var ticket [2]string
+	for dec.More() {
+		if err := expectDelim(dec, '['); err != nil {
+			return 0, err
+		}
+
+		codes := 0
+		for dec.More() {
+			token, err := dec.Token()
+			if err != nil {
+				return 0, ErrInvalidFormat
+			}
+			code, ok := token.(string)
+			if !ok {
+				return 0, ErrInvalidFormat
+			}
+			if codes < len(ticket) {
+				ticket[codes] = code
+			}
+			codes++
+		}
+
+		if err := expectDelim(dec, ']'); err != nil {
+			return 0, err
+		}
+		if codes != len(ticket) {
+			return 0, errInvalidTicketFormat
+		}
+		if err := validateTicket(ticket[0], ticket[1]); err != nil {
+			return 0, err
+		}
+		if err := visit(ticket[0], ticket[1]); err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	if err := expectDelim(dec, ']'); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// expectDelim reads the next token and fails unless it is the given delimiter
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return ErrInvalidFormat
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return ErrInvalidFormat
+	}
+	return nil
+}
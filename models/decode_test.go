@@ -0,0 +1,114 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeItineraryRequest(t *testing.T) {
+	errStop := errors.New("stop")
+
+	tests := []struct {
+		name        string
+		body        string
+		visitErr    error
+		wantTickets [][2]string
+		wantErr     error
+		wantErrText string
+	}{
+		{
+			name:        "valid tickets",
+			body:        `{"tickets": [["SFO", "LAX"], ["LAX", "JFK"]]}`,
+			wantTickets: [][2]string{{"SFO", "LAX"}, {"LAX", "JFK"}},
+		},
+		{
+			name:        "unknown fields are skipped",
+			body:        `{"meta": {"a": [1, 2]}, "tickets": [["SFO", "LAX"]], "note": "x"}`,
+			wantTickets: [][2]string{{"SFO", "LAX"}},
+		},
+		{
+			name:        "empty body",
+			body:        ``,
+			wantErrText: "no tickets provided",
+		},
+		{
+			name:        "empty tickets",
+			body:        `{"tickets": []}`,
+			wantErrText: "no tickets provided",
+		},
+		{
+			name:        "null tickets",
+			body:        `{"tickets": null}`,
+			wantErrText: "no tickets provided",
+		},
+		{
+			name:    "not an object",
+			body:    `[["SFO", "LAX"]]`,
+			wantErr: ErrInvalidFormat,
+		},
+		{
+			name:        "truncated body",
+			body:        `{"tickets": [["SFO", "LAX"]`,
+			wantTickets: [][2]string{{"SFO", "LAX"}},
+			wantErr:     ErrInvalidFormat,
+		},
+		{
+			name:    "non string airport code",
+			body:    `{"tickets": [["SFO", 42]]}`,
+			wantErr: ErrInvalidFormat,
+		},
+		{
+			name:        "wrong ticket arity",
+			body:        `{"tickets": [["SFO", "LAX", "JFK"]]}`,
+			wantErrText: "invalid ticket format: each ticket must have exactly source and destination",
+		},
+		{
+			name:        "invalid airport code stops decoding",
+			body:        `{"tickets": [["SFO", "LAX"], ["LAXX", "JFK"], ["JFK", "BOS"]]}`,
+			wantTickets: [][2]string{{"SFO", "LAX"}},
+			wantErrText: "invalid airport code: must be 3 characters",
+		},
+		{
+			name:        "visit error stops decoding",
+			body:        `{"tickets": [["SFO", "LAX"], ["LAX", "JFK"]]}`,
+			visitErr:    errStop,
+			wantTickets: [][2]string{{"SFO", "LAX"}},
+			wantErr:     errStop,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				request ItineraryRequest
+				visited [][2]string
+			)
+			err := DecodeItineraryRequest(strings.NewReader(tt.body), &request, func(src, dst string) error {
+				visited = append(visited, [2]string{src, dst})
+				return tt.visitErr
+			})
+
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("DecodeItineraryRequest() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantErrText != "":
+				if err == nil || err.Error() != tt.wantErrText {
+					t.Errorf("DecodeItineraryRequest() error = %v, want %q", err, tt.wantErrText)
+				}
+			case err != nil:
+				t.Errorf("DecodeItineraryRequest() unexpected error = %v", err)
+			}
+
+			if !reflect.DeepEqual(visited, tt.wantTickets) {
+				t.Errorf("visited tickets = %v, want %v", visited, tt.wantTickets)
+			}
+			if request.Tickets != nil {
+				t.Error("streamed tickets must not be materialised in the request")
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+// ItineraryDiffRequest represents a request comparing the itineraries of two ticket sets
+type ItineraryDiffRequest struct {
+	From ItineraryRequest `json:"from"`
+	To   ItineraryRequest `json:"to"`
+}
+
+// Reroute replaces the flights of an itinerary between two airports with another path.
+// Both paths start and end at the same airports.
+type Reroute struct {
+	From []string `json:"from"`
+	To   []string `json:"to"`
+}
+
+// AirportChange represents an airport of an itinerary replaced by another
+type AirportChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ItineraryDiff represents what changed between two itineraries. Flights taken on both are
+// left out; the flights between two airports both itineraries visit in turn are reported as
+// rerouted, and the other flights as added or removed.
+type ItineraryDiff struct {
+	FromRevision    int            `json:"from_revision,omitempty"`
+	ToRevision      int            `json:"to_revision,omitempty"`
+	From            []string       `json:"from"`
+	To              []string       `json:"to"`
+	Added           []TicketPair   `json:"added"`
+	Removed         []TicketPair   `json:"removed"`
+	Rerouted        []Reroute      `json:"rerouted"`
+	AddedAirports   []string       `json:"added_airports"`
+	RemovedAirports []string       `json:"removed_airports"`
+	Origin          *AirportChange `json:"origin,omitempty"`
+	Destination     *AirportChange `json:"destination,omitempty"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "fmt"
+
+// ValidationError reports a request that is malformed or carries invalid data. Code is a
+// stable identifier clients can rely on; Message is meant for humans and may change. Args
+// holds the values formatted into Message, so the message can be rendered in other languages.
+type ValidationError struct {
+	Code    string
+	Message string
+	Args    []interface{}
+}
+
+// Error returns the human readable message
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// newValidationError creates a validation error with the given code and message
+func newValidationError(code, message string) error {
+	return &ValidationError{Code: code, Message: message}
+}
+
+// newValidationErrorf creates a validation error whose message formats args
+func newValidationErrorf(code, format string, args ...interface{}) error {
+	return &ValidationError{Code: code, Message: fmt.Sprintf(format, args...), Args: args}
+}
+
+// Problem is an RFC 7807 problem details object, extended with the stable error code
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+}
@@ -1,13 +1,13 @@
 package models
 
-import "errors"
-
 // TicketPair represents a single flight ticket with source and destination airports
 type TicketPair []string
 
 // ItineraryRequest represents the incoming request containing flight tickets
 type ItineraryRequest struct {
-    Tickets []TicketPair `json:"tickets"`
+    Tickets    []TicketPair `json:"tickets"`
+    Passenger  string       `json:"passenger,omitempty"`
+    TravelDate string       `json:"travel_date,omitempty"`
 }
 
 // ItineraryResponse represents the API response with the ordered itinerary
@@ -15,26 +15,42 @@ type ItineraryResponse struct {
     Itinerary []string `json:"itinerary"`
 }
 
+// Validation errors shared by the buffered and streaming request decoders
+var (
+    errNoTickets           = newValidationError("no_tickets", "no tickets provided")
+    errInvalidTicketFormat = newValidationError("invalid_ticket_format", "invalid ticket format: each ticket must have exactly source and destination")
+    errEmptyAirportCode    = newValidationError("empty_airport_code", "invalid ticket: airport codes cannot be empty")
+    errInvalidAirportCode  = newValidationError("invalid_airport_code", "invalid airport code: must be 3 characters")
+)
+
 // Validate checks if the request contains valid ticket data
 func (r *ItineraryRequest) Validate() error {
     if len(r.Tickets) == 0 {
-        return errors.New("no tickets provided")
+        return errNoTickets
     }
 
     for _, ticket := range r.Tickets {
         if len(ticket) != 2 {
-            return errors.New("invalid ticket format: each ticket must have exactly source and destination")
-        }
-        if ticket[0] == "" || ticket[1] == "" {
-            return errors.New("invalid ticket: airport codes cannot be empty")
+            return errInvalidTicketFormat
         }
-        // Basic IATA airport code validation (3 uppercase letters)
-        for _, code := range ticket {
-            if len(code) != 3 {
-                return errors.New("invalid airport code: must be 3 characters")
-            }
+        if err := validateTicket(ticket[0], ticket[1]); err != nil {
+            return err
         }
     }
 
     return nil
-}
\ No newline at end of file
+}
+
+// validateTicket checks the airport codes of a single ticket
+func validateTicket(src, dst string) error {
+    if src == "" || dst == "" {
+        return errEmptyAirportCode
+    }
+    // Basic IATA airport code validation (3 uppercase letters)
+    for _, code := range [2]string{src, dst} {
+        if len(code) != 3 {
+            return errInvalidAirportCode
+        }
+    }
+    return nil
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// DateLayout is the format of travel dates
+const DateLayout = "2006-01-02"
+
+// Limits of stored itineraries and of their listing
+const (
+	MaxPassengerLength        = 200
+	DefaultItineraryListLimit = 50
+	MaxItineraryListLimit     = 500
+)
+
+// Validation errors of stored itineraries
+var (
+	ErrInvalidStore      = newValidationError("invalid_store", "invalid store parameter: must be true or false")
+	errInvalidPassenger  = newValidationErrorf("invalid_passenger", "invalid passenger: must be at most %d characters", MaxPassengerLength)
+	errInvalidTravelDate = newValidationError("invalid_travel_date", "invalid travel date: must be formatted as YYYY-MM-DD")
+	errInvalidLimit      = newValidationErrorf("invalid_limit", "invalid limit: must be between 1 and %d", MaxItineraryListLimit)
+	errEmptyPatch        = newValidationError("empty_patch", "empty patch: add, remove or replace at least one ticket")
+)
+
+// StoredItinerary represents a reconstructed itinerary kept by the service under an ID.
+// Every change to its tickets creates a new revision, numbered from 1.
+type StoredItinerary struct {
+	ID         string       `json:"id"`
+	Revision   int          `json:"revision"`
+	Passenger  string       `json:"passenger,omitempty"`
+	TravelDate string       `json:"travel_date,omitempty"`
+	Tickets    []TicketPair `json:"tickets"`
+	Itinerary  []string     `json:"itinerary"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+}
+
+// ItineraryRevisions represents the revision history of a stored itinerary, oldest first
+type ItineraryRevisions struct {
+	Revisions []StoredItinerary `json:"revisions"`
+}
+
+// TicketReplacement replaces a ticket of a stored itinerary with another
+type TicketReplacement struct {
+	From TicketPair `json:"from"`
+	To   TicketPair `json:"to"`
+}
+
+// ItineraryPatch represents a change to the tickets of a stored itinerary. Removed tickets
+// and the tickets replacements start from are taken out before the others are put in.
+type ItineraryPatch struct {
+	Add     []TicketPair        `json:"add,omitempty"`
+	Remove  []TicketPair        `json:"remove,omitempty"`
+	Replace []TicketReplacement `json:"replace,omitempty"`
+}
+
+// ItineraryQuery selects stored itineraries. Empty fields do not filter; date and time
+// ranges are inclusive of their start and exclusive of their end.
+type ItineraryQuery struct {
+	Airport       string // Visited anywhere on the itinerary
+	Origin        string
+	Destination   string
+	Passenger     string
+	TravelFrom    string
+	TravelTo      string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Cursor        string // Resumes the listing after the last itinerary of a previous page
+	Limit         int
+}
+
+// ItineraryList represents a page of stored itineraries, oldest first
+type ItineraryList struct {
+	Itineraries []StoredItinerary `json:"itineraries"`
+	NextCursor  string            `json:"next_cursor,omitempty"`
+}
+
+// ValidateDetails checks the passenger and travel date of a request whose itinerary is stored
+func (r *ItineraryRequest) ValidateDetails() error {
+	if utf8.RuneCountInString(r.Passenger) > MaxPassengerLength {
+		return errInvalidPassenger
+	}
+	if r.TravelDate != "" {
+		return validateDate(r.TravelDate)
+	}
+	return nil
+}
+
+// Validate checks the dates and limit of the query
+func (q *ItineraryQuery) Validate() error {
+	for _, date := range [2]string{q.TravelFrom, q.TravelTo} {
+		if date == "" {
+			continue
+		}
+		if err := validateDate(date); err != nil {
+			return err
+		}
+	}
+	if q.Limit < 1 || q.Limit > MaxItineraryListLimit {
+		return errInvalidLimit
+	}
+	return nil
+}
+
+// Validate checks that the patch changes at least one ticket and that every ticket is valid
+func (p *ItineraryPatch) Validate() error {
+	removed, added := p.Removed(), p.Added()
+	if len(removed) == 0 && len(added) == 0 {
+		return errEmptyPatch
+	}
+
+	for _, ticket := range append(removed, added...) {
+		if len(ticket) != 2 {
+			return errInvalidTicketFormat
+		}
+		if err := validateTicket(ticket[0], ticket[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Removed returns the tickets the patch takes out of the itinerary
+func (p *ItineraryPatch) Removed() []TicketPair {
+	removed := make([]TicketPair, 0, len(p.Remove)+len(p.Replace))
+	removed = append(removed, p.Remove...)
+	for _, replacement := range p.Replace {
+		removed = append(removed, replacement.From)
+	}
+	return removed
+}
+
+// Added returns the tickets the patch puts into the itinerary
+func (p *ItineraryPatch) Added() []TicketPair {
+	added := make([]TicketPair, 0, len(p.Add)+len(p.Replace))
+	added = append(added, p.Add...)
+	for _, replacement := range p.Replace {
+		added = append(added, replacement.To)
+	}
+	return added
+}
+
+// InvalidQueryParameter returns the error reporting a malformed query parameter
+func InvalidQueryParameter(name string) error {
+	return newValidationErrorf("invalid_query_parameter", "invalid query parameter: %s", name)
+}
+
+// validateDate checks that date is a calendar date formatted as YYYY-MM-DD
+func validateDate(date string) error {
+	if _, err := time.Parse(DateLayout, date); err != nil {
+		return errInvalidTravelDate
+	}
+	return nil
+}
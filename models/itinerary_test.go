@@ -0,0 +1,86 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestItineraryRequestValidateDetails(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  ItineraryRequest
+		wantCode string
+	}{
+		{"no details", ItineraryRequest{}, ""},
+		{"valid details", ItineraryRequest{Passenger: "Ada Lovelace", TravelDate: "2026-10-19"}, ""},
+		{"passenger too long", ItineraryRequest{Passenger: strings.Repeat("a", MaxPassengerLength+1)}, "invalid_passenger"},
+		{"malformed travel date", ItineraryRequest{TravelDate: "19/10/2026"}, "invalid_travel_date"},
+		{"impossible travel date", ItineraryRequest{TravelDate: "2026-02-30"}, "invalid_travel_date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.request.ValidateDetails()
+			if got := validationCode(err); got != tt.wantCode {
+				t.Errorf("ValidateDetails() code = %q, want %q", got, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestItineraryQueryValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    ItineraryQuery
+		wantCode string
+	}{
+		{"default query", ItineraryQuery{Limit: DefaultItineraryListLimit}, ""},
+		{"travel date range", ItineraryQuery{TravelFrom: "2026-10-01", TravelTo: "2026-11-01", Limit: 1}, ""},
+		{"malformed travel date", ItineraryQuery{TravelFrom: "october", Limit: 1}, "invalid_travel_date"},
+		{"zero limit", ItineraryQuery{}, "invalid_limit"},
+		{"limit too large", ItineraryQuery{Limit: MaxItineraryListLimit + 1}, "invalid_limit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.query.Validate()
+			if got := validationCode(err); got != tt.wantCode {
+				t.Errorf("Validate() code = %q, want %q", got, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestItineraryPatchValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    ItineraryPatch
+		wantCode string
+	}{
+		{"add", ItineraryPatch{Add: []TicketPair{{"JFK", "MIA"}}}, ""},
+		{"replace", ItineraryPatch{Replace: []TicketReplacement{{From: TicketPair{"LAX", "JFK"}, To: TicketPair{"LAX", "BOS"}}}}, ""},
+		{"empty patch", ItineraryPatch{}, "empty_patch"},
+		{"malformed ticket", ItineraryPatch{Remove: []TicketPair{{"JFK"}}}, "invalid_ticket_format"},
+		{"invalid replacement", ItineraryPatch{Replace: []TicketReplacement{{From: TicketPair{"LAX", "JFK"}, To: TicketPair{"LAX", ""}}}}, "empty_airport_code"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.patch.Validate()
+			if got := validationCode(err); got != tt.wantCode {
+				t.Errorf("Validate() code = %q, want %q", got, tt.wantCode)
+			}
+		})
+	}
+}
+
+// validationCode returns the code of a validation error, or the empty string for nil
+func validationCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	if validationErr, ok := err.(*ValidationError); ok {
+		return validationErr.Code
+	}
+	return "not a validation error: " + err.Error()
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"net/url"
+	"time"
+)
+
+// JobRequest represents a request to reconstruct itineraries asynchronously.
+// It carries either a single ticket set or a batch of independent ticket sets.
+type JobRequest struct {
+	Tickets     []TicketPair       `json:"tickets,omitempty"`
+	Requests    []ItineraryRequest `json:"requests,omitempty"`
+	CallbackURL string             `json:"callback_url,omitempty"`
+}
+
+// JobProgress reports how many ticket sets of a job have been processed
+type JobProgress struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+}
+
+// JobResponse represents the API response describing the state of an asynchronous job
+type JobResponse struct {
+	ID          string            `json:"id"`
+	Status      string            `json:"status"`
+	Progress    JobProgress       `json:"progress"`
+	Itinerary   []string          `json:"itinerary,omitempty"`
+	Results     []BatchItemResult `json:"results,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	Code        string            `json:"code,omitempty"`
+	CallbackURL string            `json:"callback_url,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	StartedAt   *time.Time        `json:"started_at,omitempty"`
+	FinishedAt  *time.Time        `json:"finished_at,omitempty"`
+}
+
+// JobEvent represents a progress event of a running job, sent as Server-Sent Events data
+type JobEvent struct {
+	Item     int         `json:"item"`
+	Stage    string      `json:"stage"`
+	Count    int         `json:"count"`
+	Progress JobProgress `json:"progress"`
+}
+
+// DeliveryAttempt describes a single attempt to deliver a job completion callback
+type DeliveryAttempt struct {
+	Attempt    int       `json:"attempt"`
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// DeliveriesResponse represents the API response listing callback delivery attempts of a job
+type DeliveriesResponse struct {
+	JobID       string            `json:"job_id"`
+	CallbackURL string            `json:"callback_url"`
+	Status      string            `json:"status"`
+	Attempts    []DeliveryAttempt `json:"attempts"`
+}
+
+// Job validation errors
+var (
+	errAmbiguousJob       = newValidationError("ambiguous_job", "provide either tickets or requests, not both")
+	errInvalidCallbackURL = newValidationError("invalid_callback_url", "invalid callback URL: must be an absolute http or https URL")
+)
+
+// IsBatch reports whether the job carries several independent ticket sets
+func (r *JobRequest) IsBatch() bool {
+	return r.Requests != nil
+}
+
+// ItineraryRequests returns the ticket sets of the job in submission order
+func (r *JobRequest) ItineraryRequests() []ItineraryRequest {
+	if r.IsBatch() {
+		return r.Requests
+	}
+	return []ItineraryRequest{{Tickets: r.Tickets}}
+}
+
+// Validate checks the job envelope; a single ticket set is validated as a whole while
+// batch items are validated individually when the job runs
+func (r *JobRequest) Validate(maxItems int) error {
+	if r.Tickets != nil && r.Requests != nil {
+		return errAmbiguousJob
+	}
+
+	if r.CallbackURL != "" {
+		callback, err := url.Parse(r.CallbackURL)
+		if err != nil || (callback.Scheme != "http" && callback.Scheme != "https") || callback.Host == "" {
+			return errInvalidCallbackURL
+		}
+	}
+
+	if !r.IsBatch() {
+		single := ItineraryRequest{Tickets: r.Tickets}
+		return single.Validate()
+	}
+
+	if len(r.Requests) == 0 {
+		return errNoTicketSets
+	}
+	if maxItems > 0 && len(r.Requests) > maxItems {
+		return tooManyTicketSets(maxItems)
+	}
+	return nil
+}
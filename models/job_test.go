@@ -0,0 +1,77 @@
+package models
+
+import "testing"
+
+func TestJobRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request JobRequest
+		wantErr bool
+	}{
+		{
+			name:    "valid single ticket set",
+			request: JobRequest{Tickets: []TicketPair{{"SFO", "LAX"}}},
+			wantErr: false,
+		},
+		{
+			name:    "invalid single ticket set",
+			request: JobRequest{Tickets: []TicketPair{{"SFO"}}},
+			wantErr: true,
+		},
+		{
+			name: "tickets and requests together",
+			request: JobRequest{
+				Tickets:  []TicketPair{{"SFO", "LAX"}},
+				Requests: []ItineraryRequest{{Tickets: []TicketPair{{"SFO", "LAX"}}}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "empty batch",
+			request: JobRequest{Requests: []ItineraryRequest{}},
+			wantErr: true,
+		},
+		{
+			name: "batch too large",
+			request: JobRequest{Requests: []ItineraryRequest{
+				{Tickets: []TicketPair{{"SFO", "LAX"}}},
+				{Tickets: []TicketPair{{"SFO", "LAX"}}},
+				{Tickets: []TicketPair{{"SFO", "LAX"}}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "valid callback URL",
+			request: JobRequest{
+				Tickets:     []TicketPair{{"SFO", "LAX"}},
+				CallbackURL: "https://example.com/hooks/itinerary",
+			},
+			wantErr: false,
+		},
+		{
+			name: "relative callback URL",
+			request: JobRequest{
+				Tickets:     []TicketPair{{"SFO", "LAX"}},
+				CallbackURL: "/hooks/itinerary",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported callback scheme",
+			request: JobRequest{
+				Tickets:     []TicketPair{{"SFO", "LAX"}},
+				CallbackURL: "ftp://example.com/hooks",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.request.Validate(2)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("JobRequest.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
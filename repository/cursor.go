@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"flight-itinerary-api/models"
+)
+
+// ErrInvalidCursor is returned for cursors the repository did not issue
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// position locates an itinerary in the creation order, ties broken by ID
+type position struct {
+	createdAt time.Time
+	id        string
+}
+
+// positionOf returns the position of itinerary
+func positionOf(itinerary *models.StoredItinerary) position {
+	return position{createdAt: itinerary.CreatedAt, id: itinerary.ID}
+}
+
+// less reports whether p comes before q
+func (p position) less(q position) bool {
+	if !p.createdAt.Equal(q.createdAt) {
+		return p.createdAt.Before(q.createdAt)
+	}
+	return p.id < q.id
+}
+
+// before reports whether p comes before itinerary
+func (p position) before(itinerary *models.StoredItinerary) bool {
+	return p.less(positionOf(itinerary))
+}
+
+// search returns the index of the first itinerary of list, ordered by creation, that does not
+// come before p
+func (p position) search(list []*models.StoredItinerary) int {
+	return sort.Search(len(list), func(i int) bool {
+		return !positionOf(list[i]).less(p)
+	})
+}
+
+// encodeCursor returns the opaque cursor resuming a listing after itinerary
+func encodeCursor(itinerary *models.StoredItinerary) string {
+	raw := strconv.FormatInt(itinerary.CreatedAt.UnixNano(), 10) + ":" + itinerary.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor returns the position a cursor resumes after, or nil for an empty cursor
+func decodeCursor(cursor string) (*position, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	nanos, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+	createdAt, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &position{createdAt: time.Unix(0, createdAt), id: id}, nil
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"flight-itinerary-api/models"
+)
+
+// FileRepository keeps itineraries in memory and persists them to an append-only file of
+// JSON records, one per revision and line, which is replayed when the repository is opened.
+type FileRepository struct {
+	mu     sync.Mutex // Serialises writes so the file and memory agree on their order
+	file   *os.File
+	size   int64 // Length of the complete records written so far
+	memory *MemoryRepository
+}
+
+// OpenFileRepository opens the repository persisted at path, creating it if needed
+func OpenFileRepository(path string) (*FileRepository, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening itinerary file: %w", err)
+	}
+
+	r := &FileRepository{file: file, memory: NewMemoryRepository()}
+	if err := r.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// load replays the records of the file into memory. A partial last record, left by a crash
+// in the middle of a write, is discarded.
+func (r *FileRepository) load() error {
+	reader := bufio.NewReader(r.file)
+	var offset int64
+	for line := 1; ; line++ {
+		record, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(record) > 0 {
+				if err := r.file.Truncate(offset); err != nil {
+					return fmt.Errorf("discarding partial itinerary record: %w", err)
+				}
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading itinerary file: %w", err)
+		}
+
+		var itinerary models.StoredItinerary
+		if err := json.Unmarshal(record, &itinerary); err != nil {
+			return fmt.Errorf("corrupt itinerary record on line %d: %w", line, err)
+		}
+		// Records written before itineraries had revisions are their first revision
+		if itinerary.Revision == 0 {
+			itinerary.Revision, itinerary.UpdatedAt = 1, itinerary.CreatedAt
+		}
+		r.memory.put(&itinerary)
+		offset += int64(len(record))
+	}
+
+	r.size = offset
+	if _, err := r.file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("seeking itinerary file: %w", err)
+	}
+	return nil
+}
+
+// Create stores a new itinerary, returning once it is durably written
+func (r *FileRepository) Create(ctx context.Context, itinerary *models.StoredItinerary) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.memory.Get(ctx, itinerary.ID); err == nil {
+		return ErrExists
+	}
+	if err := r.append(itinerary); err != nil {
+		return err
+	}
+	return r.memory.Create(ctx, itinerary)
+}
+
+// Update stores a new revision of an existing itinerary, returning once it is durably written
+func (r *FileRepository) Update(ctx context.Context, itinerary *models.StoredItinerary) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.memory.mu.RLock()
+	err := r.memory.checkRevision(itinerary)
+	r.memory.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if err := r.append(itinerary); err != nil {
+		return err
+	}
+	return r.memory.Update(ctx, itinerary)
+}
+
+// Get returns the latest revision of the itinerary of id
+func (r *FileRepository) Get(ctx context.Context, id string) (*models.StoredItinerary, error) {
+	return r.memory.Get(ctx, id)
+}
+
+// Revisions returns every revision of the itinerary of id
+func (r *FileRepository) Revisions(ctx context.Context, id string) ([]*models.StoredItinerary, error) {
+	return r.memory.Revisions(ctx, id)
+}
+
+// List returns the page of itineraries matching query
+func (r *FileRepository) List(ctx context.Context, query *models.ItineraryQuery) (*models.ItineraryList, error) {
+	return r.memory.List(ctx, query)
+}
+
+// Close closes the file
+func (r *FileRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.file.Close()
+}
+
+// append writes itinerary as the last record of the file and syncs it to disk. A failed
+// write is rolled back so that the next record does not follow a partial one.
+func (r *FileRepository) append(itinerary *models.StoredItinerary) error {
+	record, err := json.Marshal(itinerary)
+	if err != nil {
+		return err
+	}
+	record = append(record, '\n')
+
+	_, err = r.file.Write(record)
+	if err == nil {
+		err = r.file.Sync()
+	}
+	if err != nil {
+		if rollbackErr := r.rollback(); rollbackErr != nil {
+			err = errors.Join(err, rollbackErr)
+		}
+		return fmt.Errorf("writing itinerary record: %w", err)
+	}
+
+	r.size += int64(len(record))
+	return nil
+}
+
+// rollback discards whatever follows the last complete record
+func (r *FileRepository) rollback() error {
+	if err := r.file.Truncate(r.size); err != nil {
+		return err
+	}
+	_, err := r.file.Seek(r.size, io.SeekStart)
+	return err
+}
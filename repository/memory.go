@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"flight-itinerary-api/models"
+)
+
+// indexField names a field of stored itineraries with a secondary index
+type indexField int
+
+// Indexed fields
+const (
+	indexAirport indexField = iota
+	indexOrigin
+	indexDestination
+	indexPassenger
+)
+
+// indexKey identifies the itineraries sharing a value of an indexed field
+type indexKey struct {
+	field indexField
+	value string
+}
+
+// MemoryRepository keeps itineraries in memory, ordered by creation and indexed by the
+// airports they visit, their origin, destination and passenger; they are lost when the
+// process exits. Only the latest revision of an itinerary is indexed.
+type MemoryRepository struct {
+	mu          sync.RWMutex
+	itineraries map[string]*models.StoredItinerary     // Latest revisions
+	history     map[string][]*models.StoredItinerary   // Every revision, oldest first
+	ordered     []*models.StoredItinerary              // By creation time, then ID
+	indexes     map[indexKey][]*models.StoredItinerary // Each in the same order
+}
+
+// NewMemoryRepository creates an empty in-memory repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		itineraries: make(map[string]*models.StoredItinerary),
+		history:     make(map[string][]*models.StoredItinerary),
+		indexes:     make(map[indexKey][]*models.StoredItinerary),
+	}
+}
+
+// Create stores a new itinerary
+func (r *MemoryRepository) Create(_ context.Context, itinerary *models.StoredItinerary) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.itineraries[itinerary.ID]; found {
+		return ErrExists
+	}
+	r.put(clone(itinerary))
+	return nil
+}
+
+// Update stores a new revision of an existing itinerary
+func (r *MemoryRepository) Update(_ context.Context, itinerary *models.StoredItinerary) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkRevision(itinerary); err != nil {
+		return err
+	}
+	r.put(clone(itinerary))
+	return nil
+}
+
+// Get returns the latest revision of the itinerary of id
+func (r *MemoryRepository) Get(_ context.Context, id string) (*models.StoredItinerary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	itinerary, found := r.itineraries[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+	return clone(itinerary), nil
+}
+
+// Revisions returns every revision of the itinerary of id
+func (r *MemoryRepository) Revisions(_ context.Context, id string) ([]*models.StoredItinerary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history, found := r.history[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+	revisions := make([]*models.StoredItinerary, len(history))
+	for i, revision := range history {
+		revisions[i] = clone(revision)
+	}
+	return revisions, nil
+}
+
+// List returns the page of itineraries matching query. It scans the shortest index the
+// query selects, from the cursor or the start of the creation range, so a page costs time
+// proportional to the itineraries of that index skipped by the other filters.
+func (r *MemoryRepository) List(_ context.Context, query *models.ItineraryQuery) (*models.ItineraryList, error) {
+	after, err := decodeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	candidates := r.ordered
+	for _, key := range queryKeys(query) {
+		if list := r.indexes[key]; len(list) < len(candidates) {
+			candidates = list
+		}
+	}
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(candidates), func(i int) bool { return after.before(candidates[i]) })
+	}
+	if !query.CreatedAfter.IsZero() {
+		start = max(start, sort.Search(len(candidates), func(i int) bool {
+			return !candidates[i].CreatedAt.Before(query.CreatedAfter)
+		}))
+	}
+
+	list := &models.ItineraryList{Itineraries: []models.StoredItinerary{}}
+	for _, itinerary := range candidates[start:] {
+		if !query.CreatedBefore.IsZero() && !itinerary.CreatedAt.Before(query.CreatedBefore) {
+			break
+		}
+		if !matches(itinerary, query) {
+			continue
+		}
+		if query.Limit > 0 && len(list.Itineraries) == query.Limit {
+			list.NextCursor = encodeCursor(&list.Itineraries[len(list.Itineraries)-1])
+			break
+		}
+		list.Itineraries = append(list.Itineraries, *clone(itinerary))
+	}
+	return list, nil
+}
+
+// Close does nothing; the itineraries are simply dropped with the repository
+func (r *MemoryRepository) Close() error {
+	return nil
+}
+
+// checkRevision reports whether itinerary directly follows the latest stored revision of its
+// ID. The caller holds a lock.
+func (r *MemoryRepository) checkRevision(itinerary *models.StoredItinerary) error {
+	current, found := r.itineraries[itinerary.ID]
+	if !found {
+		return ErrNotFound
+	}
+	if itinerary.Revision != current.Revision+1 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// put stores itinerary as the latest revision of its ID, unindexing the previous one. The
+// caller holds the write lock, or has the repository to itself.
+func (r *MemoryRepository) put(itinerary *models.StoredItinerary) {
+	if previous, found := r.itineraries[itinerary.ID]; found {
+		r.ordered = removeSorted(r.ordered, previous)
+		for _, key := range indexKeys(previous) {
+			if list := removeSorted(r.indexes[key], previous); len(list) > 0 {
+				r.indexes[key] = list
+			} else {
+				delete(r.indexes, key)
+			}
+		}
+	}
+
+	r.itineraries[itinerary.ID] = itinerary
+	r.history[itinerary.ID] = append(r.history[itinerary.ID], itinerary)
+	r.ordered = insertSorted(r.ordered, itinerary)
+	for _, key := range indexKeys(itinerary) {
+		r.indexes[key] = insertSorted(r.indexes[key], itinerary)
+	}
+}
+
+// indexKeys returns the index entries of itinerary
+func indexKeys(itinerary *models.StoredItinerary) []indexKey {
+	var keys []indexKey
+	seen := make(map[string]bool, len(itinerary.Itinerary))
+	for _, airport := range itinerary.Itinerary {
+		airport = normalizeAirport(airport)
+		if !seen[airport] {
+			seen[airport] = true
+			keys = append(keys, indexKey{indexAirport, airport})
+		}
+	}
+	if n := len(itinerary.Itinerary); n > 0 {
+		keys = append(keys,
+			indexKey{indexOrigin, normalizeAirport(itinerary.Itinerary[0])},
+			indexKey{indexDestination, normalizeAirport(itinerary.Itinerary[n-1])},
+		)
+	}
+	if itinerary.Passenger != "" {
+		keys = append(keys, indexKey{indexPassenger, normalizePassenger(itinerary.Passenger)})
+	}
+	return keys
+}
+
+// queryKeys returns the index entries every itinerary matching query has
+func queryKeys(query *models.ItineraryQuery) []indexKey {
+	var keys []indexKey
+	if query.Airport != "" {
+		keys = append(keys, indexKey{indexAirport, normalizeAirport(query.Airport)})
+	}
+	if query.Origin != "" {
+		keys = append(keys, indexKey{indexOrigin, normalizeAirport(query.Origin)})
+	}
+	if query.Destination != "" {
+		keys = append(keys, indexKey{indexDestination, normalizeAirport(query.Destination)})
+	}
+	if query.Passenger != "" {
+		keys = append(keys, indexKey{indexPassenger, normalizePassenger(query.Passenger)})
+	}
+	return keys
+}
+
+// matches reports whether itinerary passes every filter of query except the creation range
+func matches(itinerary *models.StoredItinerary, query *models.ItineraryQuery) bool {
+	airports := itinerary.Itinerary
+	if query.Airport != "" && !slices.ContainsFunc(airports, func(airport string) bool {
+		return strings.EqualFold(airport, query.Airport)
+	}) {
+		return false
+	}
+	if query.Origin != "" && (len(airports) == 0 || !strings.EqualFold(airports[0], query.Origin)) {
+		return false
+	}
+	if query.Destination != "" && (len(airports) == 0 || !strings.EqualFold(airports[len(airports)-1], query.Destination)) {
+		return false
+	}
+	if query.Passenger != "" && normalizePassenger(itinerary.Passenger) != normalizePassenger(query.Passenger) {
+		return false
+	}
+
+	// Dates formatted as YYYY-MM-DD sort like the days they name
+	if query.TravelFrom != "" && (itinerary.TravelDate == "" || itinerary.TravelDate < query.TravelFrom) {
+		return false
+	}
+	if query.TravelTo != "" && (itinerary.TravelDate == "" || itinerary.TravelDate >= query.TravelTo) {
+		return false
+	}
+	return true
+}
+
+// normalizeAirport makes airport codes match regardless of case
+func normalizeAirport(code string) string {
+	return strings.ToUpper(code)
+}
+
+// normalizePassenger makes passenger names match regardless of case and surrounding spaces
+func normalizePassenger(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// insertSorted inserts itinerary into list, keeping it ordered by creation
+func insertSorted(list []*models.StoredItinerary, itinerary *models.StoredItinerary) []*models.StoredItinerary {
+	at := positionOf(itinerary).search(list)
+	list = append(list, nil)
+	copy(list[at+1:], list[at:])
+	list[at] = itinerary
+	return list
+}
+
+// removeSorted removes itinerary from list, which is ordered by creation
+func removeSorted(list []*models.StoredItinerary, itinerary *models.StoredItinerary) []*models.StoredItinerary {
+	at := positionOf(itinerary).search(list)
+	if at < len(list) && list[at] == itinerary {
+		list = append(list[:at], list[at+1:]...)
+	}
+	return list
+}
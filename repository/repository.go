@@ -0,0 +1,60 @@
+// Package repository stores reconstructed itineraries
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/models"
+)
+
+// Repository errors
+var (
+	ErrNotFound = errors.New("itinerary not found")
+	ErrExists   = errors.New("itinerary already exists")
+	ErrConflict = errors.New("itinerary revision conflict")
+)
+
+// Repository stores itineraries by ID. Itineraries passed in and returned are copies, so
+// callers may keep and modify them.
+type Repository interface {
+	// Create stores a new itinerary, failing with ErrExists if its ID is taken
+	Create(ctx context.Context, itinerary *models.StoredItinerary) error
+	// Update stores a new revision of an existing itinerary, failing with ErrConflict unless
+	// it directly follows the latest stored revision
+	Update(ctx context.Context, itinerary *models.StoredItinerary) error
+	// Get returns the latest revision of the itinerary of id, or ErrNotFound
+	Get(ctx context.Context, id string) (*models.StoredItinerary, error)
+	// Revisions returns every revision of the itinerary of id, oldest first, or ErrNotFound
+	Revisions(ctx context.Context, id string) ([]*models.StoredItinerary, error)
+	// List returns the page of latest revisions of itineraries matching query, ordered by creation time then
+	// ID, with the cursor of the next page if there is one. A zero limit lists them all.
+	List(ctx context.Context, query *models.ItineraryQuery) (*models.ItineraryList, error)
+	// Close releases the resources held by the repository
+	Close() error
+}
+
+// New opens the repository of the configured backend
+func New(cfg *config.StorageConfig) (Repository, error) {
+	switch cfg.Backend {
+	case config.StorageMemory:
+		return NewMemoryRepository(), nil
+	case config.StorageFile:
+		return OpenFileRepository(cfg.Path)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// clone returns a deep copy of itinerary
+func clone(itinerary *models.StoredItinerary) *models.StoredItinerary {
+	c := *itinerary
+	c.Tickets = make([]models.TicketPair, len(itinerary.Tickets))
+	for i, ticket := range itinerary.Tickets {
+		c.Tickets[i] = append(models.TicketPair(nil), ticket...)
+	}
+	c.Itinerary = append([]string(nil), itinerary.Itinerary...)
+	return &c
+}
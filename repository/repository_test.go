@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/models"
+)
+
+func TestRepository(t *testing.T) {
+	backends := []struct {
+		name string
+		cfg  config.StorageConfig
+	}{
+		{"memory", config.StorageConfig{Backend: config.StorageMemory}},
+		{"file", config.StorageConfig{Backend: config.StorageFile, Path: filepath.Join(t.TempDir(), "itineraries.jsonl")}},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			repo, err := New(&backend.cfg)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			defer repo.Close()
+
+			ctx := context.Background()
+			itinerary := &models.StoredItinerary{
+				ID:        "a",
+				Tickets:   []models.TicketPair{{"SFO", "LAX"}, {"LAX", "JFK"}},
+				Itinerary: []string{"SFO", "LAX", "JFK"},
+				CreatedAt: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+			}
+			if err := repo.Create(ctx, itinerary); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if err := repo.Create(ctx, itinerary); !errors.Is(err, ErrExists) {
+				t.Errorf("Create() twice error = %v, want ErrExists", err)
+			}
+
+			// The repository keeps its own copy
+			itinerary.Itinerary[0] = "OAK"
+			got, err := repo.Get(ctx, "a")
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Itinerary, []string{"SFO", "LAX", "JFK"}) {
+				t.Errorf("Get() itinerary = %v, modified by the caller", got.Itinerary)
+			}
+
+			if _, err := repo.Get(ctx, "b"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get(unknown) error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestFileRepositoryReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "itineraries.jsonl")
+	ctx := context.Background()
+
+	repo, err := OpenFileRepository(path)
+	if err != nil {
+		t.Fatalf("OpenFileRepository() error = %v", err)
+	}
+	created := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	want := &models.StoredItinerary{
+		ID:        "a",
+		Revision:  1,
+		Tickets:   []models.TicketPair{{"SFO", "LAX"}},
+		Itinerary: []string{"SFO", "LAX"},
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+	if err := repo.Create(ctx, want); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	repo.Close()
+
+	// Simulate a crash in the middle of writing the next record
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"id":"b","tick`)
+	file.Close()
+
+	repo, err = OpenFileRepository(path)
+	if err != nil {
+		t.Fatalf("OpenFileRepository() after crash error = %v", err)
+	}
+	defer repo.Close()
+
+	got, err := repo.Get(ctx, "a")
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() = %+v, %v, want %+v", got, err, want)
+	}
+	if _, err := repo.Get(ctx, "b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(partial) error = %v, want ErrNotFound", err)
+	}
+
+	// Records written after recovery follow the last complete one
+	if err := repo.Create(ctx, &models.StoredItinerary{ID: "c"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	repo.Close()
+	repo, err = OpenFileRepository(path)
+	if err != nil {
+		t.Fatalf("OpenFileRepository() error = %v", err)
+	}
+	defer repo.Close()
+	if _, err := repo.Get(ctx, "c"); err != nil {
+		t.Errorf("Get(c) error = %v", err)
+	}
+}
+
+func TestRepositoryUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "itineraries.jsonl")
+	backends := []struct {
+		name string
+		cfg  config.StorageConfig
+	}{
+		{"memory", config.StorageConfig{Backend: config.StorageMemory}},
+		{"file", config.StorageConfig{Backend: config.StorageFile, Path: path}},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			repo, err := New(&backend.cfg)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			defer repo.Close()
+
+			ctx := context.Background()
+			created := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+			first := &models.StoredItinerary{ID: "a", Revision: 1, Tickets: []models.TicketPair{{"SFO", "LAX"}}, Itinerary: []string{"SFO", "LAX"}, CreatedAt: created, UpdatedAt: created}
+			if err := repo.Create(ctx, first); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			second := &models.StoredItinerary{ID: "a", Revision: 2, Tickets: []models.TicketPair{{"SFO", "JFK"}}, Itinerary: []string{"SFO", "JFK"}, CreatedAt: created, UpdatedAt: created.Add(time.Hour)}
+			if err := repo.Update(ctx, second); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if err := repo.Update(ctx, second); !errors.Is(err, ErrConflict) {
+				t.Errorf("Update() twice error = %v, want ErrConflict", err)
+			}
+			if err := repo.Update(ctx, &models.StoredItinerary{ID: "b", Revision: 2}); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Update(unknown) error = %v, want ErrNotFound", err)
+			}
+
+			got, err := repo.Get(ctx, "a")
+			if err != nil || !reflect.DeepEqual(got, second) {
+				t.Errorf("Get() = %+v, %v, want %+v", got, err, second)
+			}
+			revisions, err := repo.Revisions(ctx, "a")
+			if err != nil || !reflect.DeepEqual(revisions, []*models.StoredItinerary{first, second}) {
+				t.Errorf("Revisions() = %+v, %v", revisions, err)
+			}
+
+			// Only the latest revision is searchable
+			for airport, want := range map[string]int{"LAX": 0, "JFK": 1} {
+				list, err := repo.List(ctx, &models.ItineraryQuery{Airport: airport})
+				if err != nil || len(list.Itineraries) != want {
+					t.Errorf("List(%s) = %+v, %v, want %d itineraries", airport, list, err, want)
+				}
+			}
+
+			if backend.cfg.Backend != config.StorageFile {
+				return
+			}
+			repo.Close()
+			repo, err = OpenFileRepository(path)
+			if err != nil {
+				t.Fatalf("OpenFileRepository() error = %v", err)
+			}
+			revisions, err = repo.Revisions(ctx, "a")
+			if err != nil || !reflect.DeepEqual(revisions, []*models.StoredItinerary{first, second}) {
+				t.Errorf("Revisions() after reopening = %+v, %v", revisions, err)
+			}
+		})
+	}
+}
+
+func TestRepositoryList(t *testing.T) {
+	backends := []struct {
+		name string
+		cfg  config.StorageConfig
+	}{
+		{"memory", config.StorageConfig{Backend: config.StorageMemory}},
+		{"file", config.StorageConfig{Backend: config.StorageFile, Path: filepath.Join(t.TempDir(), "itineraries.jsonl")}},
+	}
+
+	created := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	itineraries := []*models.StoredItinerary{
+		{ID: "a", Passenger: "Ada", TravelDate: "2026-10-20", Itinerary: []string{"SFO", "LAX", "JFK"}, CreatedAt: created},
+		{ID: "b", Passenger: "Grace", TravelDate: "2026-10-21", Itinerary: []string{"LAX", "DXB"}, CreatedAt: created.Add(time.Hour)},
+		{ID: "c", Passenger: "ada ", TravelDate: "2026-11-02", Itinerary: []string{"JFK", "LAX"}, CreatedAt: created.Add(2 * time.Hour)},
+		{ID: "d", Itinerary: []string{"CDG", "FCO"}, CreatedAt: created.Add(2 * time.Hour)},
+	}
+
+	tests := []struct {
+		name  string
+		query models.ItineraryQuery
+		want  []string
+	}{
+		{"everything", models.ItineraryQuery{}, []string{"a", "b", "c", "d"}},
+		{"airport visited", models.ItineraryQuery{Airport: "lax"}, []string{"a", "b", "c"}},
+		{"origin", models.ItineraryQuery{Origin: "LAX"}, []string{"b"}},
+		{"destination", models.ItineraryQuery{Destination: "LAX"}, []string{"c"}},
+		{"passenger", models.ItineraryQuery{Passenger: "ADA"}, []string{"a", "c"}},
+		{"travel dates", models.ItineraryQuery{TravelFrom: "2026-10-21", TravelTo: "2026-11-02"}, []string{"b"}},
+		{"creation time", models.ItineraryQuery{CreatedAfter: created.Add(time.Hour), CreatedBefore: created.Add(2 * time.Hour)}, []string{"b"}},
+		{"combined filters", models.ItineraryQuery{Airport: "JFK", Passenger: "ada", CreatedAfter: created.Add(time.Minute)}, []string{"c"}},
+		{"unknown airport", models.ItineraryQuery{Airport: "NRT"}, nil},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			repo, err := New(&backend.cfg)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			defer repo.Close()
+
+			ctx := context.Background()
+			for _, itinerary := range itineraries {
+				if err := repo.Create(ctx, itinerary); err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					list, err := repo.List(ctx, &tt.query)
+					if err != nil {
+						t.Fatalf("List() error = %v", err)
+					}
+					if got := listedIDs(list); !reflect.DeepEqual(got, tt.want) {
+						t.Errorf("List() = %v, want %v", got, tt.want)
+					}
+				})
+			}
+
+			// Pages resume after the cursor of the previous one
+			var pages [][]string
+			query := models.ItineraryQuery{Airport: "LAX", Limit: 2}
+			for {
+				list, err := repo.List(ctx, &query)
+				if err != nil {
+					t.Fatalf("List() error = %v", err)
+				}
+				pages = append(pages, listedIDs(list))
+				if list.NextCursor == "" {
+					break
+				}
+				query.Cursor = list.NextCursor
+			}
+			if want := [][]string{{"a", "b"}, {"c"}}; !reflect.DeepEqual(pages, want) {
+				t.Errorf("pages = %v, want %v", pages, want)
+			}
+
+			if _, err := repo.List(ctx, &models.ItineraryQuery{Cursor: "not a cursor"}); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("List(invalid cursor) error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+
+	// The indexes of the file backend are rebuilt when it is reopened
+	repo, err := OpenFileRepository(backends[1].cfg.Path)
+	if err != nil {
+		t.Fatalf("OpenFileRepository() error = %v", err)
+	}
+	defer repo.Close()
+	list, err := repo.List(context.Background(), &models.ItineraryQuery{Passenger: "ada"})
+	if err != nil || !reflect.DeepEqual(listedIDs(list), []string{"a", "c"}) {
+		t.Errorf("List() after reopening = %v, %v", listedIDs(list), err)
+	}
+}
+
+// listedIDs returns the IDs of the itineraries of list in order
+func listedIDs(list *models.ItineraryList) []string {
+	if list == nil {
+		return nil
+	}
+	var ids []string
+	for _, itinerary := range list.Itineraries {
+		ids = append(ids, itinerary.ID)
+	}
+	return ids
+}
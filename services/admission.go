@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/metrics"
+)
+
+// ErrOverloaded is returned when a reconstruction is shed because the worker pool is saturated
+var ErrOverloaded = NewError(KindUnavailable, "overloaded", "service overloaded, retry later")
+
+// defaultRetryAfter is suggested to shed callers when no delay is configured
+const defaultRetryAfter = time.Second
+
+// queueWaitDecay is the weight of the newest sample in the queue wait average (1/8)
+const queueWaitDecay = 3
+
+// admission bounds the number of reconstructions handed to the worker pool and decides which
+// callers may wait for a worker and which are shed right away.
+//
+// A caller finding every worker busy waits at most maxWait, and only while fewer than maxQueue
+// callers are already waiting. In non-blocking mode, or while the average queue wait of its
+// priority lane exceeds targetWait, it is shed immediately instead, so the service fails fast
+// under sustained load. A freed worker always goes to the most urgent lane with callers
+// waiting, and within a lane it is shared fairly between clients according to their weights.
+type admission struct {
+	mu      sync.Mutex
+	free    int
+	waiting int
+	lanes   [numPriorities]*lane
+
+	weights     map[string]int
+	maxQueue    int
+	maxWait     time.Duration
+	nonBlocking bool
+	targetWait  time.Duration
+	retryAfter  time.Duration
+
+	busy metrics.Gauge
+}
+
+// lane queues the callers of one priority class
+type lane struct {
+	queue     *fairQueue
+	queueWait atomic.Int64 // moving average of the queue wait, in nanoseconds
+
+	admitted  metrics.Counter
+	shed      metrics.Counter
+	waitTotal metrics.Counter
+	waiting   metrics.Gauge
+}
+
+// waiter is a caller waiting for a worker; ready is closed once granted is set
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+
+	// Fair queuing position, see fairQueue
+	client        string
+	start, finish float64
+	seq           uint64
+}
+
+// newAdmission creates an admission controller handing out the given number of workers,
+// recording its metrics under the given pool name
+func newAdmission(workers int, cfg *config.AdmissionConfig, registry *metrics.Registry, pool string) *admission {
+	retryAfter := cfg.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = defaultRetryAfter
+	}
+
+	a := &admission{
+		free:        workers,
+		weights:     cfg.ClientWeights,
+		maxQueue:    cfg.MaxQueueDepth,
+		maxWait:     cfg.MaxQueueWait,
+		nonBlocking: cfg.NonBlocking,
+		targetWait:  cfg.TargetLatency,
+		retryAfter:  retryAfter,
+		busy:        registry.Gauge("itinerary_workers_busy", "Reconstructions currently holding a worker.", "pool", pool),
+	}
+	for p := range a.lanes {
+		priority := Priority(p).String()
+		a.lanes[p] = &lane{
+			queue:     newFairQueue(),
+			admitted:  registry.Counter("itinerary_admitted_total", "Reconstructions admitted to the worker pool.", "pool", pool, "priority", priority),
+			shed:      registry.Counter("itinerary_shed_total", "Reconstructions shed because the worker pool was saturated.", "pool", pool, "priority", priority),
+			waitTotal: registry.Counter("itinerary_queue_wait_seconds_total", "Time spent waiting for a worker.", "pool", pool, "priority", priority),
+			waiting:   registry.Gauge("itinerary_queue_waiting", "Reconstructions currently waiting for a worker.", "pool", pool, "priority", priority),
+		}
+	}
+	return a
+}
+
+// acquire reserves a worker for the caller, waiting within the configured limits.
+// It returns ErrOverloaded when the caller is shed, or the context's error.
+func (a *admission) acquire(ctx context.Context) error {
+	return a.admit(ctx, true)
+}
+
+// acquireWait reserves a worker for the caller, waiting for as long as ctx allows. It is used
+// by streams and jobs, which apply their own flow control and are never shed.
+func (a *admission) acquireWait(ctx context.Context) error {
+	return a.admit(ctx, false)
+}
+
+// admit reserves a worker in the lane of the caller's priority, shedding the caller when
+// sheddable and the limits are exceeded
+func (a *admission) admit(ctx context.Context, sheddable bool) error {
+	l := a.lanes[PriorityFromContext(ctx)]
+
+	a.mu.Lock()
+	if a.free > 0 {
+		a.free--
+		a.mu.Unlock()
+		a.admitted(l, 0)
+		return nil
+	}
+
+	if sheddable && (a.nonBlocking || a.congested(l) || (a.maxQueue > 0 && a.waiting >= a.maxQueue)) {
+		a.mu.Unlock()
+		l.shed.Inc()
+		return ErrOverloaded
+	}
+
+	client := ClientFromContext(ctx)
+	w := &waiter{ready: make(chan struct{})}
+	l.queue.push(w, client, a.weight(client))
+	a.waiting++
+	l.waiting.Inc()
+	a.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if sheddable && a.maxWait > 0 {
+		timer := time.NewTimer(a.maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	start := time.Now()
+	var err error
+	select {
+	case <-w.ready:
+		a.admitted(l, time.Since(start))
+		return nil
+	case <-timeout:
+		err = ErrOverloaded
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	a.mu.Lock()
+	if w.granted {
+		// The worker was handed over as the caller gave up; take it rather than lose it
+		a.mu.Unlock()
+		a.admitted(l, time.Since(start))
+		return nil
+	}
+	a.dequeue(l, w)
+	a.mu.Unlock()
+
+	if err == ErrOverloaded {
+		a.observe(l, time.Since(start))
+		l.shed.Inc()
+	}
+	return err
+}
+
+// release returns a worker reserved by acquire or acquireWait, handing it straight to the
+// next caller of the most urgent lane with callers waiting
+func (a *admission) release() {
+	a.busy.Dec()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, l := range a.lanes {
+		w := l.queue.pop()
+		if w == nil {
+			continue
+		}
+		a.waiting--
+		l.waiting.Dec()
+		w.granted = true
+		close(w.ready)
+		return
+	}
+	a.free++
+}
+
+// dequeue removes a waiter that gave up from its lane; the lock must be held
+func (a *admission) dequeue(l *lane, w *waiter) {
+	if l.queue.remove(w) {
+		a.waiting--
+		l.waiting.Dec()
+	}
+}
+
+// weight returns the configured share of a client, defaulting to 1
+func (a *admission) weight(client string) int {
+	if weight, ok := a.weights[client]; ok && weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// admitted records a caller that obtained a worker after waiting for wait
+func (a *admission) admitted(l *lane, wait time.Duration) {
+	a.busy.Inc()
+	l.admitted.Inc()
+	l.waitTotal.Add(wait.Seconds())
+	a.observe(l, wait)
+}
+
+// congested reports whether callers of the lane recently waited longer than the target on average
+func (a *admission) congested(l *lane) bool {
+	return a.targetWait > 0 && time.Duration(l.queueWait.Load()) > a.targetWait
+}
+
+// observe folds a queue wait into the lane's moving average
+func (a *admission) observe(l *lane, wait time.Duration) {
+	for {
+		old := l.queueWait.Load()
+		updated := old + (int64(wait)-old)>>queueWaitDecay
+		if l.queueWait.CompareAndSwap(old, updated) {
+			return
+		}
+	}
+}
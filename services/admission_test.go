@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/metrics"
+)
+
+func TestAdmission(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.AdmissionConfig
+		waiters int // callers already waiting for the only worker
+		wantErr error
+	}{
+		{
+			name:    "waits until the deadline",
+			cfg:     config.AdmissionConfig{MaxQueueWait: 10 * time.Millisecond},
+			wantErr: ErrOverloaded,
+		},
+		{
+			name:    "non-blocking mode sheds immediately",
+			cfg:     config.AdmissionConfig{NonBlocking: true},
+			wantErr: ErrOverloaded,
+		},
+		{
+			name:    "full queue sheds immediately",
+			cfg:     config.AdmissionConfig{MaxQueueDepth: 1},
+			waiters: 1,
+			wantErr: ErrOverloaded,
+		},
+		{
+			name:    "unbounded wait follows the context",
+			cfg:     config.AdmissionConfig{},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAdmission(1, &tt.cfg, metrics.NewRegistry(), poolSmall)
+			if err := a.acquire(context.Background()); err != nil {
+				t.Fatalf("acquire() error = %v", err)
+			}
+			defer a.release()
+
+			waitersCtx, cancelWaiters := context.WithCancel(context.Background())
+			defer cancelWaiters()
+			for i := 0; i < tt.waiters; i++ {
+				go func() { _ = a.acquire(waitersCtx) }()
+			}
+			for queued(a) < tt.waiters {
+				time.Sleep(time.Millisecond)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			err := a.acquire(ctx)
+			if err != tt.wantErr {
+				t.Fatalf("acquire() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.cfg.NonBlocking || tt.waiters > 0 {
+				if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+					t.Errorf("acquire() took %v, want an immediate rejection", elapsed)
+				}
+			}
+		})
+	}
+}
+
+func TestAdmissionAdaptiveShedding(t *testing.T) {
+	a := newAdmission(1, &config.AdmissionConfig{TargetLatency: 5 * time.Millisecond}, metrics.NewRegistry(), poolSmall)
+	if err := a.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	// Long queue waits push the average over the target
+	interactive := a.lanes[PriorityInteractive]
+	for i := 0; i < 16; i++ {
+		a.observe(interactive, 50*time.Millisecond)
+	}
+
+	if err := a.acquire(context.Background()); err != ErrOverloaded {
+		t.Fatalf("acquire() error = %v, want %v", err, ErrOverloaded)
+	}
+
+	// A free worker is always handed out, and quick admissions bring the average back down
+	a.release()
+	for i := 0; i < 64; i++ {
+		if err := a.acquire(context.Background()); err != nil {
+			t.Fatalf("acquire() error = %v", err)
+		}
+		a.release()
+	}
+	if a.congested(interactive) {
+		t.Errorf("congested() = true after the queue drained, average %v", time.Duration(interactive.queueWait.Load()))
+	}
+}
+
+func TestAdmissionAcquireWait(t *testing.T) {
+	a := newAdmission(1, &config.AdmissionConfig{NonBlocking: true}, metrics.NewRegistry(), poolSmall)
+	if err := a.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	// Streams wait for the busy worker instead of being shed
+	done := make(chan error, 1)
+	go func() { done <- a.acquireWait(context.Background()) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("acquireWait() returned %v while the worker was busy", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	a.release()
+	if err := <-done; err != nil {
+		t.Fatalf("acquireWait() error = %v", err)
+	}
+	a.release()
+}
+
+func TestAdmissionPriority(t *testing.T) {
+	registry := metrics.NewRegistry()
+	a := newAdmission(1, &config.AdmissionConfig{}, registry, poolSmall)
+	if err := a.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	// Bulk work queues first, interactive work arrives later
+	order := make(chan Priority, 2)
+	for _, p := range []Priority{PriorityBulk, PriorityInteractive} {
+		want := queued(a) + 1
+		go func() {
+			if err := a.acquire(WithPriority(context.Background(), p)); err != nil {
+				t.Errorf("acquire(%v) error = %v", p, err)
+				return
+			}
+			order <- p
+			a.release()
+		}()
+		for queued(a) < want {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	a.release()
+	if first, second := <-order, <-order; first != PriorityInteractive || second != PriorityBulk {
+		t.Errorf("admission order = %v, %v, want interactive before bulk", first, second)
+	}
+
+	for _, p := range []Priority{PriorityInteractive, PriorityBulk} {
+		if got := registry.Counter("itinerary_admitted_total", "", "pool", poolSmall, "priority", p.String()).Value(); got == 0 {
+			t.Errorf("itinerary_admitted_total{priority=%q} = 0", p)
+		}
+	}
+}
+
+// queued returns the number of callers waiting for a worker
+func queued(a *admission) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.waiting
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"sync"
+	"time"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/metrics"
+	"flight-itinerary-api/models"
+)
+
+// cacheKeyVersion namespaces cache keys, and changes whenever the options or the canonical
+// form of ticket sets entering the key change
+const cacheKeyVersion = 1
+
+// CacheStatus reports how the result cache served a reconstruction
+type CacheStatus string
+
+// Cache statuses
+const (
+	CacheHit    CacheStatus = "HIT"
+	CacheMiss   CacheStatus = "MISS"
+	CacheBypass CacheStatus = "BYPASS"
+)
+
+// cacheBypassKey is the context key marking reconstructions that must not be served from the cache
+type cacheBypassKey struct{}
+
+// cacheStatusKey is the context key under which the cache status listener is stored
+type cacheStatusKey struct{}
+
+// WithCacheBypass returns a context whose reconstructions are computed afresh rather than served
+// from the result cache; their results still refresh the cache
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// CacheBypassFromContext reports whether ctx asks for the result cache to be bypassed
+func CacheBypassFromContext(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// WithCacheStatus returns a context that makes the service report to fn how the result cache
+// served a single ticket set reconstruction. fn is not called when the cache is disabled.
+func WithCacheStatus(ctx context.Context, fn func(CacheStatus)) context.Context {
+	return context.WithValue(ctx, cacheStatusKey{}, fn)
+}
+
+// reportCacheStatus calls the cache status listener carried by ctx, if any
+func reportCacheStatus(ctx context.Context, status CacheStatus) {
+	if fn, ok := ctx.Value(cacheStatusKey{}).(func(CacheStatus)); ok && fn != nil {
+		fn(status)
+	}
+}
+
+// cacheKey identifies a ticket set regardless of the order of its tickets
+type cacheKey [sha256.Size]byte
+
+// resultCache is an LRU cache of reconstruction results with a time to live. Cached
+// itineraries are shared between callers and must never be modified.
+type resultCache struct {
+	mu         sync.Mutex
+	entries    map[cacheKey]*list.Element
+	lru        *list.List // Most recently used first
+	maxEntries int
+	maxTickets int
+	ttl        time.Duration
+	now        func() time.Time
+
+	hits      metrics.Counter
+	misses    metrics.Counter
+	bypassed  metrics.Counter
+	evictions metrics.Counter
+	size      metrics.Gauge
+}
+
+// cacheEntry is a cached reconstruction outcome
+type cacheEntry struct {
+	key       cacheKey
+	itinerary []string
+	err       error
+	expires   time.Time
+}
+
+// newResultCache creates a result cache, or returns nil when it is disabled
+func newResultCache(cfg *config.CacheConfig, registry *metrics.Registry) *resultCache {
+	if cfg.MaxEntries <= 0 {
+		return nil
+	}
+
+	return &resultCache{
+		entries:    make(map[cacheKey]*list.Element),
+		lru:        list.New(),
+		maxEntries: cfg.MaxEntries,
+		maxTickets: cfg.MaxTickets,
+		ttl:        cfg.TTL,
+		now:        time.Now,
+		hits:       registry.Counter("itinerary_cache_hits_total", "Reconstructions served from the result cache."),
+		misses:     registry.Counter("itinerary_cache_misses_total", "Cacheable reconstructions not found in the result cache."),
+		bypassed:   registry.Counter("itinerary_cache_bypassed_total", "Reconstructions that bypassed the result cache on request."),
+		evictions:  registry.Counter("itinerary_cache_evictions_total", "Result cache entries evicted to make room for new ones."),
+		size:       registry.Gauge("itinerary_cache_entries", "Entries currently held by the result cache."),
+	}
+}
+
+// get returns a copy of the live entry of key, or nil when there is none
+func (c *resultCache) get(key cacheKey) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, found := c.entries[key]
+	if !found {
+		c.misses.Inc()
+		return nil
+	}
+
+	entry := *element.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.remove(element)
+		c.misses.Inc()
+		return nil
+	}
+
+	c.lru.MoveToFront(element)
+	c.hits.Inc()
+	return &entry
+}
+
+// put stores the outcome of key, evicting the least recently used entry when the cache is full.
+// Only successes and invalid input errors are stored, since they are the same on every attempt.
+func (c *resultCache) put(key cacheKey, itinerary []string, err error) {
+	if err != nil && ErrorKindOf(err) != KindInvalidInput {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if element, found := c.entries[key]; found {
+		entry := element.Value.(*cacheEntry)
+		entry.itinerary, entry.err, entry.expires = itinerary, err, expires
+		c.lru.MoveToFront(element)
+		return
+	}
+
+	for c.lru.Len() >= c.maxEntries {
+		c.remove(c.lru.Back())
+		c.evictions.Inc()
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, itinerary: itinerary, err: err, expires: expires})
+	c.size.Set(float64(c.lru.Len()))
+}
+
+// remove drops element from the cache
+func (c *resultCache) remove(element *list.Element) {
+	delete(c.entries, element.Value.(*cacheEntry).key)
+	c.lru.Remove(element)
+	c.size.Set(float64(c.lru.Len()))
+}
+
+// cacheable reports whether a ticket set of the given size may be cached
+func (c *resultCache) cacheable(tickets int) bool {
+	return c != nil && tickets <= c.maxTickets
+}
+
+// ticketSetKey hashes the canonical form of a ticket set: its tickets sorted by source then
+// destination, each airport code prefixed with its length so that no two ticket sets share
+// an encoding. The itinerary of a ticket set does not depend on the order of its tickets.
+func ticketSetKey(tickets []models.TicketPair) cacheKey {
+	sorted := make([]models.TicketPair, len(tickets))
+	copy(sorted, tickets)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i][0] != sorted[j][0] {
+			return sorted[i][0] < sorted[j][0]
+		}
+		return sorted[i][1] < sorted[j][1]
+	})
+
+	h := sha256.New()
+	var buf [binary.MaxVarintLen64]byte
+	h.Write(buf[:binary.PutUvarint(buf[:], cacheKeyVersion)])
+	h.Write(buf[:binary.PutUvarint(buf[:], uint64(len(sorted)))])
+	for _, ticket := range sorted {
+		for _, code := range ticket {
+			h.Write(buf[:binary.PutUvarint(buf[:], uint64(len(code)))])
+			h.Write([]byte(code))
+		}
+	}
+
+	var key cacheKey
+	h.Sum(key[:0])
+	return key
+}
+
+// keyFor returns the key of a ticket set, or the zero key when neither the result cache nor
+// request coalescing accepts ticket sets of its size
+func (s *ItineraryService) keyFor(tickets []models.TicketPair) cacheKey {
+	if !s.keyable(len(tickets)) {
+		return cacheKey{}
+	}
+	return ticketSetKey(tickets)
+}
+
+// keyable reports whether the key of a ticket set of the given size is worth computing
+func (s *ItineraryService) keyable(tickets int) bool {
+	return s.cache.cacheable(tickets) || s.coalescer.coalescable(tickets)
+}
+
+// lookup returns the cached outcome of a ticket set of the given size and key, or nil on a
+// miss. A miss also returns a function storing the outcome once computed, which does nothing
+// for ticket sets that are not cacheable.
+func (s *ItineraryService) lookup(ctx context.Context, tickets int, key cacheKey) (*cacheEntry, func([]string, error)) {
+	if !s.cache.cacheable(tickets) {
+		if s.cache != nil {
+			reportCacheStatus(ctx, CacheMiss)
+		}
+		return nil, func([]string, error) {}
+	}
+
+	store := func(itinerary []string, err error) {
+		s.cache.put(key, itinerary, err)
+	}
+
+	if CacheBypassFromContext(ctx) {
+		s.cache.bypassed.Inc()
+		reportCacheStatus(ctx, CacheBypass)
+		return nil, store
+	}
+
+	if entry := s.cache.get(key); entry != nil {
+		reportCacheStatus(ctx, CacheHit)
+		return entry, nil
+	}
+	reportCacheStatus(ctx, CacheMiss)
+	return nil, store
+}
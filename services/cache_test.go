@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/metrics"
+	"flight-itinerary-api/models"
+)
+
+func TestTicketSetKey(t *testing.T) {
+	key := ticketSetKey([]models.TicketPair{{"SFO", "LAX"}, {"LAX", "JFK"}})
+
+	tests := []struct {
+		name     string
+		tickets  []models.TicketPair
+		wantSame bool
+	}{
+		{"same order", []models.TicketPair{{"SFO", "LAX"}, {"LAX", "JFK"}}, true},
+		{"reordered tickets", []models.TicketPair{{"LAX", "JFK"}, {"SFO", "LAX"}}, true},
+		{"different ticket", []models.TicketPair{{"SFO", "LAX"}, {"LAX", "JFO"}}, false},
+		{"reversed ticket", []models.TicketPair{{"LAX", "SFO"}, {"LAX", "JFK"}}, false},
+		{"ambiguous concatenation", []models.TicketPair{{"SFOL", "AX"}, {"LAX", "JFK"}}, false},
+		{"extra ticket", []models.TicketPair{{"SFO", "LAX"}, {"LAX", "JFK"}, {"JFK", "MCO"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ticketSetKey(tt.tickets) == key; got != tt.wantSame {
+				t.Errorf("ticketSetKey() matches = %v, want %v", got, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestResultCache(t *testing.T) {
+	registry := metrics.NewRegistry()
+	cache := newResultCache(&config.CacheConfig{MaxEntries: 2, MaxTickets: 10, TTL: time.Minute}, registry)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	keyA := ticketSetKey([]models.TicketPair{{"SFO", "LAX"}})
+	keyB := ticketSetKey([]models.TicketPair{{"LAX", "JFK"}})
+	keyC := ticketSetKey([]models.TicketPair{{"JFK", "MCO"}})
+
+	cache.put(keyA, []string{"SFO", "LAX"}, nil)
+	cache.put(keyB, nil, errMultipleStarts)
+	if entry := cache.get(keyA); entry == nil || !reflect.DeepEqual(entry.itinerary, []string{"SFO", "LAX"}) {
+		t.Fatalf("get(A) = %+v, want cached itinerary", entry)
+	}
+	if entry := cache.get(keyB); entry == nil || entry.err != errMultipleStarts {
+		t.Fatalf("get(B) = %+v, want cached invalid input error", entry)
+	}
+
+	// B is now the most recently used, so storing C evicts A
+	cache.get(keyB)
+	cache.put(keyC, []string{"JFK", "MCO"}, nil)
+	if cache.get(keyA) != nil {
+		t.Error("get(A) found the least recently used entry after eviction")
+	}
+	if cache.get(keyB) == nil || cache.get(keyC) == nil {
+		t.Error("get() lost a recently used entry")
+	}
+
+	// Transient failures are never cached
+	cache.put(keyA, nil, ErrOverloaded)
+	cache.put(keyA, nil, ErrProcessingTimeout)
+	if cache.get(keyA) != nil {
+		t.Error("get(A) found a transient failure")
+	}
+
+	// Entries expire after the TTL
+	now = now.Add(time.Minute)
+	if cache.get(keyB) != nil {
+		t.Error("get(B) found an expired entry")
+	}
+
+	if got := cache.evictions.Value(); got != 1 {
+		t.Errorf("evictions = %v, want 1", got)
+	}
+	if got := cache.size.Value(); got != 1 {
+		t.Errorf("entries = %v, want 1", got)
+	}
+	if got := cache.hits.Value(); got != 5 {
+		t.Errorf("hits = %v, want 5", got)
+	}
+	if got := cache.misses.Value(); got != 3 {
+		t.Errorf("misses = %v, want 3", got)
+	}
+}
+
+func TestReconstructItineraryCache(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 2,
+		},
+		Cache: config.CacheConfig{
+			MaxEntries: 10,
+			MaxTickets: 2,
+			TTL:        time.Minute,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	// reconstruct runs a reconstruction and returns how the cache served it
+	reconstruct := func(ctx context.Context, tickets ...models.TicketPair) ([]string, CacheStatus) {
+		var status CacheStatus
+		ctx = WithCacheStatus(ctx, func(s CacheStatus) { status = s })
+		itinerary, err := service.ReconstructItinerary(ctx, &models.ItineraryRequest{Tickets: tickets})
+		if err != nil {
+			t.Fatalf("ReconstructItinerary() error = %v", err)
+		}
+		return itinerary, status
+	}
+
+	want := []string{"SFO", "LAX", "JFK"}
+	if got, status := reconstruct(context.Background(), models.TicketPair{"SFO", "LAX"}, models.TicketPair{"LAX", "JFK"}); status != CacheMiss || !reflect.DeepEqual(got, want) {
+		t.Errorf("first reconstruction = %v (%s), want %v (MISS)", got, status, want)
+	}
+	if got, status := reconstruct(context.Background(), models.TicketPair{"LAX", "JFK"}, models.TicketPair{"SFO", "LAX"}); status != CacheHit || !reflect.DeepEqual(got, want) {
+		t.Errorf("reordered reconstruction = %v (%s), want %v (HIT)", got, status, want)
+	}
+	if _, status := reconstruct(WithCacheBypass(context.Background()), models.TicketPair{"SFO", "LAX"}, models.TicketPair{"LAX", "JFK"}); status != CacheBypass {
+		t.Errorf("bypassed reconstruction status = %s, want BYPASS", status)
+	}
+
+	// Ticket sets larger than the limit are never cached
+	large := []models.TicketPair{{"SFO", "LAX"}, {"LAX", "JFK"}, {"JFK", "MCO"}}
+	reconstruct(context.Background(), large...)
+	if _, status := reconstruct(context.Background(), large...); status != CacheMiss {
+		t.Errorf("large reconstruction status = %s, want MISS", status)
+	}
+
+	// Batches and decoded requests share the cache
+	results := service.ReconstructBatch(context.Background(), []models.ItineraryRequest{
+		{Tickets: []models.TicketPair{{"LAX", "JFK"}, {"SFO", "LAX"}}},
+		{Tickets: []models.TicketPair{{"SFO", "LAX"}, {"JFK", "MCO"}}},
+	})
+	if !reflect.DeepEqual(results[0].Itinerary, want) || results[1].Err != errMultipleStarts {
+		t.Fatalf("ReconstructBatch() = %+v", results)
+	}
+
+	var status CacheStatus
+	statusCtx := WithCacheStatus(context.Background(), func(s CacheStatus) { status = s })
+	_, err := service.ReconstructFromSource(statusCtx, func(visit func(src, dst string) error) error {
+		if err := visit("SFO", "LAX"); err != nil {
+			return err
+		}
+		return visit("JFK", "MCO")
+	})
+	if err != errMultipleStarts || status != CacheHit {
+		t.Errorf("ReconstructFromSource() = %v (%s), want cached error (HIT)", err, status)
+	}
+
+	if got := service.cache.hits.Value(); got != 3 {
+		t.Errorf("hits = %v, want 3", got)
+	}
+	if got := service.cache.bypassed.Value(); got != 1 {
+		t.Errorf("bypassed = %v, want 1", got)
+	}
+}
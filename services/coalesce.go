@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/metrics"
+)
+
+// coalescer lets identical ticket sets submitted while one of them is being reconstructed
+// share that reconstruction instead of occupying a worker each
+type coalescer struct {
+	mu         sync.Mutex
+	calls      map[cacheKey]*sharedCall
+	maxTickets int
+
+	leaders   metrics.Counter
+	coalesced metrics.Counter
+	waiting   metrics.Gauge
+}
+
+// sharedCall is a reconstruction whose outcome is shared by every caller that joined it
+type sharedCall struct {
+	group  *coalescer
+	key    cacheKey
+	done   chan struct{}
+	result taskResult
+}
+
+// newCoalescer creates a coalescer, or returns nil when coalescing is disabled
+func newCoalescer(cfg *config.CoalescingConfig, registry *metrics.Registry) *coalescer {
+	if cfg.MaxTickets <= 0 {
+		return nil
+	}
+
+	return &coalescer{
+		calls:      make(map[cacheKey]*sharedCall),
+		maxTickets: cfg.MaxTickets,
+		leaders:    registry.Counter("itinerary_coalesce_leaders_total", "Coalescable reconstructions run, whether or not identical requests joined them."),
+		coalesced:  registry.Counter("itinerary_coalesced_total", "Requests served by an identical reconstruction already in flight."),
+		waiting:    registry.Gauge("itinerary_coalesce_waiting", "Requests currently following an identical reconstruction in flight."),
+	}
+}
+
+// coalescable reports whether a ticket set of the given size may be coalesced
+func (g *coalescer) coalescable(tickets int) bool {
+	return g != nil && tickets <= g.maxTickets
+}
+
+// join returns the call reconstructing the ticket set of key and whether the caller leads
+// it. A leader must run the reconstruction and land the call with its outcome; the others
+// wait for it. Ticket sets that are not coalescable get a nil call, which they lead alone.
+func (g *coalescer) join(tickets int, key cacheKey) (*sharedCall, bool) {
+	if !g.coalescable(tickets) {
+		return nil, true
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if call, found := g.calls[key]; found {
+		g.waiting.Inc()
+		return call, false
+	}
+
+	call := &sharedCall{group: g, key: key, done: make(chan struct{})}
+	g.calls[key] = call
+	g.leaders.Inc()
+	return call, true
+}
+
+// land records the outcome of the call and releases the callers waiting for it. Later
+// identical ticket sets start a new call.
+func (c *sharedCall) land(itinerary []string, err error) {
+	if c == nil {
+		return
+	}
+
+	c.group.mu.Lock()
+	delete(c.group.calls, c.key)
+	c.group.mu.Unlock()
+
+	c.result = taskResult{itinerary: itinerary, err: err}
+	close(c.done)
+}
+
+// wait waits for the outcome of the call or the cancellation of ctx, and must be called once
+// by every caller that joined the call without leading it. It reports false when the leader
+// failed for reasons of its own, such as its caller going away or being shed, in which case
+// the caller must reconstruct the ticket set itself.
+func (c *sharedCall) wait(ctx context.Context) (taskResult, bool) {
+	defer c.group.waiting.Dec()
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		return taskResult{err: ctx.Err()}, true
+	}
+
+	if err := c.result.err; err != nil {
+		switch ErrorKindOf(err) {
+		case KindInvalidInput, KindInternal:
+		default:
+			return taskResult{}, false
+		}
+	}
+
+	c.group.coalesced.Inc()
+	return c.result, true
+}
+
+// lead returns the call the caller must lead to reconstruct a ticket set of the given size
+// and key, after waiting for any identical reconstruction in flight. When the outcome of one
+// of those can be shared, it is returned instead of a call.
+func (s *ItineraryService) lead(ctx context.Context, tickets int, key cacheKey) (*sharedCall, *taskResult) {
+	for {
+		call, leader := s.coalescer.join(tickets, key)
+		if leader {
+			return call, nil
+		}
+		if result, shared := call.wait(ctx); shared {
+			return nil, &result
+		}
+	}
+}
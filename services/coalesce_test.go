@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/models"
+)
+
+// waitForGauge polls until the metric value returns want, failing the test after a second
+func waitForGauge(t *testing.T, name string, value func() float64, want float64) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for value() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s = %v, want %v", name, value(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// stallingContext returns a context whose reconstruction of the ticket set at index blocks
+// once its tickets are parsed, until release is closed
+func stallingContext(ctx context.Context, index int, release <-chan struct{}) context.Context {
+	return WithProgress(ctx, func(i int, stage Stage, _ int) {
+		if i == index && stage == StageTicketsParsed {
+			<-release
+		}
+	})
+}
+
+func TestReconstructItineraryCoalescing(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 4,
+		},
+		Coalescing: config.CoalescingConfig{
+			MaxTickets: 10,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	request := &models.ItineraryRequest{Tickets: []models.TicketPair{{"LAX", "JFK"}, {"SFO", "LAX"}}}
+	reordered := &models.ItineraryRequest{Tickets: []models.TicketPair{{"SFO", "LAX"}, {"LAX", "JFK"}}}
+	want := []string{"SFO", "LAX", "JFK"}
+
+	// The leader stalls in its reconstruction while identical requests arrive
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	results := make([][]string, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int, ctx context.Context, request *models.ItineraryRequest) {
+			defer wg.Done()
+			itinerary, err := service.ReconstructItinerary(ctx, request)
+			if err != nil {
+				t.Errorf("ReconstructItinerary() error = %v", err)
+			}
+			results[i] = itinerary
+		}(i, stallingContext(context.Background(), 0, release), request)
+
+		if i == 0 {
+			waitForGauge(t, "leaders", service.coalescer.leaders.Value, 1)
+			request = reordered
+		}
+	}
+
+	waitForGauge(t, "waiting requests", service.coalescer.waiting.Value, 3)
+	close(release)
+	wg.Wait()
+
+	for i, itinerary := range results {
+		if !reflect.DeepEqual(itinerary, want) {
+			t.Errorf("request %d itinerary = %v, want %v", i, itinerary, want)
+		}
+	}
+	if got := service.coalescer.leaders.Value(); got != 1 {
+		t.Errorf("leaders = %v, want 1", got)
+	}
+	if got := service.coalescer.coalesced.Value(); got != 3 {
+		t.Errorf("coalesced = %v, want 3", got)
+	}
+	if len(service.coalescer.calls) != 0 {
+		t.Errorf("%d calls still in flight", len(service.coalescer.calls))
+	}
+}
+
+func TestReconstructItineraryCoalescingLeaderGone(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 2,
+		},
+		Coalescing: config.CoalescingConfig{
+			MaxTickets: 10,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	request := &models.ItineraryRequest{Tickets: []models.TicketPair{{"SFO", "JFK"}}}
+
+	// The leader's client goes away while another request waits for its reconstruction
+	release := make(chan struct{})
+	leaderCtx, leaderCancel := context.WithCancel(stallingContext(context.Background(), 0, release))
+	leaderDone := make(chan error)
+	go func() {
+		_, err := service.ReconstructItinerary(leaderCtx, request)
+		leaderDone <- err
+	}()
+	waitForGauge(t, "leaders", service.coalescer.leaders.Value, 1)
+
+	followerDone := make(chan []string)
+	go func() {
+		itinerary, err := service.ReconstructItinerary(context.Background(), request)
+		if err != nil {
+			t.Errorf("follower error = %v", err)
+		}
+		followerDone <- itinerary
+	}()
+	waitForGauge(t, "waiting requests", service.coalescer.waiting.Value, 1)
+
+	leaderCancel()
+	close(release)
+	if err := <-leaderDone; err == nil {
+		t.Error("cancelled leader succeeded")
+	}
+
+	// The follower does not inherit the leader's failure but reconstructs on its own
+	if got := <-followerDone; !reflect.DeepEqual(got, []string{"SFO", "JFK"}) {
+		t.Errorf("follower itinerary = %v", got)
+	}
+	if got := service.coalescer.coalesced.Value(); got != 0 {
+		t.Errorf("coalesced = %v, want 0", got)
+	}
+}
+
+func TestReconstructBatchCoalescing(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 2,
+		},
+		Coalescing: config.CoalescingConfig{
+			MaxTickets: 10,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	// The first ticket set stalls until its duplicates have joined it
+	release := make(chan struct{})
+	go func() {
+		defer close(release)
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if service.coalescer.waiting.Value() == 2 {
+				return
+			}
+		}
+	}()
+
+	results := service.ReconstructBatch(stallingContext(context.Background(), 0, release), []models.ItineraryRequest{
+		{Tickets: []models.TicketPair{{"LAX", "JFK"}, {"SFO", "LAX"}}},
+		{Tickets: []models.TicketPair{{"SFO", "LAX"}, {"LAX", "JFK"}}},
+		{Tickets: []models.TicketPair{{"SFO", "LAX"}, {"JFK", "MCO"}}},
+		{Tickets: []models.TicketPair{{"LAX", "JFK"}, {"SFO", "LAX"}}},
+	})
+
+	want := []BatchResult{
+		{Itinerary: []string{"SFO", "LAX", "JFK"}},
+		{Itinerary: []string{"SFO", "LAX", "JFK"}},
+		{Err: errMultipleStarts},
+		{Itinerary: []string{"SFO", "LAX", "JFK"}},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("ReconstructBatch() = %+v, want %+v", results, want)
+	}
+	if got := service.coalescer.leaders.Value(); got != 2 {
+		t.Errorf("leaders = %v, want 2", got)
+	}
+	if got := service.coalescer.coalesced.Value(); got != 2 {
+		t.Errorf("coalesced = %v, want 2", got)
+	}
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+
+	"flight-itinerary-api/models"
+)
+
+// Diff reconstructs the itineraries of both ticket sets of request and returns the changes
+// between them, failing with the error of the first ticket set that cannot be reconstructed
+func (s *ItineraryService) Diff(ctx context.Context, request *models.ItineraryDiffRequest) (*models.ItineraryDiff, error) {
+	results := s.ReconstructBatch(ctx, []models.ItineraryRequest{request.From, request.To})
+	for _, result := range results {
+		if result.Err != nil {
+			return nil, result.Err
+		}
+	}
+	return DiffItineraries(results[0].Itinerary, results[1].Itinerary), nil
+}
+
+// DiffItineraries returns the changes turning the itinerary from into the itinerary to.
+// Flights taken on both are unchanged, as many times as both take them. The other flights
+// are cut into runs of consecutive flights at the airports both visit; a run of from and a
+// run of to between the same two airports are a reroute, and the flights of the runs left
+// over are removed or added.
+func DiffItineraries(from, to []string) *models.ItineraryDiff {
+	fromAirports, toAirports := airportSet(from), airportSet(to)
+	diff := &models.ItineraryDiff{
+		From:            from,
+		To:              to,
+		Added:           []models.TicketPair{},
+		Removed:         []models.TicketPair{},
+		Rerouted:        []models.Reroute{},
+		AddedAirports:   missingAirports(to, fromAirports),
+		RemovedAirports: missingAirports(from, toAirports),
+	}
+
+	fromRuns := changedRuns(from, keptLegs(from, to), toAirports)
+	toRuns := changedRuns(to, keptLegs(to, from), fromAirports)
+
+	// Pair the runs of both itineraries leaving and reaching the same airports, in route order
+	byEnds := make(map[[2]string][]int, len(toRuns))
+	for i, run := range toRuns {
+		ends := runEnds(run)
+		byEnds[ends] = append(byEnds[ends], i)
+	}
+	paired := make([]bool, len(toRuns))
+	for _, run := range fromRuns {
+		ends := runEnds(run)
+		if candidates := byEnds[ends]; len(candidates) > 0 {
+			byEnds[ends] = candidates[1:]
+			paired[candidates[0]] = true
+			diff.Rerouted = append(diff.Rerouted, models.Reroute{From: run, To: toRuns[candidates[0]]})
+			continue
+		}
+		diff.Removed = appendLegs(diff.Removed, run)
+	}
+	for i, run := range toRuns {
+		if !paired[i] {
+			diff.Added = appendLegs(diff.Added, run)
+		}
+	}
+
+	if len(from) > 0 && len(to) > 0 {
+		if from[0] != to[0] {
+			diff.Origin = &models.AirportChange{From: from[0], To: to[0]}
+		}
+		if last, newLast := from[len(from)-1], to[len(to)-1]; last != newLast {
+			diff.Destination = &models.AirportChange{From: last, To: newLast}
+		}
+	}
+	return diff
+}
+
+// keptLegs reports, for each flight of itinerary, whether other takes it too. A flight taken
+// several times is kept as many times as other takes it, earliest first.
+func keptLegs(itinerary, other []string) []bool {
+	remaining := make(map[[2]string]int, len(other))
+	for i := 0; i+1 < len(other); i++ {
+		remaining[[2]string{other[i], other[i+1]}]++
+	}
+
+	kept := make([]bool, max(len(itinerary)-1, 0))
+	for i := range kept {
+		leg := [2]string{itinerary[i], itinerary[i+1]}
+		if remaining[leg] > 0 {
+			remaining[leg]--
+			kept[i] = true
+		}
+	}
+	return kept
+}
+
+// changedRuns returns the runs of consecutive flights of itinerary that are not kept, each as
+// the airports it visits. Runs stop at the airports shared with the other itinerary, so that
+// each one can be matched on its own.
+func changedRuns(itinerary []string, kept []bool, shared map[string]bool) [][]string {
+	var runs [][]string
+	lo := -1
+	for i := 0; i <= len(kept); i++ {
+		if lo >= 0 && (i == len(kept) || kept[i] || shared[itinerary[i]]) {
+			runs = append(runs, itinerary[lo:i+1])
+			lo = -1
+		}
+		if lo < 0 && i < len(kept) && !kept[i] {
+			lo = i
+		}
+	}
+	return runs
+}
+
+// runEnds returns the airports a run leaves and reaches
+func runEnds(run []string) [2]string {
+	return [2]string{run[0], run[len(run)-1]}
+}
+
+// appendLegs appends the flights of run to legs
+func appendLegs(legs []models.TicketPair, run []string) []models.TicketPair {
+	for i := 0; i+1 < len(run); i++ {
+		legs = append(legs, models.TicketPair{run[i], run[i+1]})
+	}
+	return legs
+}
+
+// airportSet returns the airports itinerary visits
+func airportSet(itinerary []string) map[string]bool {
+	airports := make(map[string]bool, len(itinerary))
+	for _, airport := range itinerary {
+		airports[airport] = true
+	}
+	return airports
+}
+
+// missingAirports returns the airports itinerary visits outside of skip, in the order
+// itinerary first visits them
+func missingAirports(itinerary []string, skip map[string]bool) []string {
+	missing := []string{}
+	seen := make(map[string]bool)
+	for _, airport := range itinerary {
+		if !skip[airport] && !seen[airport] {
+			seen[airport] = true
+			missing = append(missing, airport)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/models"
+)
+
+func TestDiffItineraries(t *testing.T) {
+	tests := []struct {
+		name            string
+		from, to        []string
+		wantAdded       []models.TicketPair
+		wantRemoved     []models.TicketPair
+		wantRerouted    []models.Reroute
+		wantAirports    [2][]string // Added and removed airports
+		wantOrigin      *models.AirportChange
+		wantDestination *models.AirportChange
+	}{
+		{
+			name: "unchanged",
+			from: []string{"SFO", "LAX", "JFK"},
+			to:   []string{"SFO", "LAX", "JFK"},
+		},
+		{
+			name:         "rerouted through another airport",
+			from:         []string{"SFO", "LAX", "JFK", "MIA"},
+			to:           []string{"SFO", "DEN", "JFK", "MIA"},
+			wantRerouted: []models.Reroute{{From: []string{"SFO", "LAX", "JFK"}, To: []string{"SFO", "DEN", "JFK"}}},
+			wantAirports: [2][]string{{"DEN"}, {"LAX"}},
+		},
+		{
+			name:         "connection added to a direct flight",
+			from:         []string{"SFO", "JFK"},
+			to:           []string{"SFO", "ORD", "JFK"},
+			wantRerouted: []models.Reroute{{From: []string{"SFO", "JFK"}, To: []string{"SFO", "ORD", "JFK"}}},
+			wantAirports: [2][]string{{"ORD"}, {}},
+		},
+		{
+			name:            "flight added at the end",
+			from:            []string{"SFO", "LAX"},
+			to:              []string{"SFO", "LAX", "JFK"},
+			wantAdded:       []models.TicketPair{{"LAX", "JFK"}},
+			wantAirports:    [2][]string{{"JFK"}, {}},
+			wantDestination: &models.AirportChange{From: "LAX", To: "JFK"},
+		},
+		{
+			name:         "origin replaced",
+			from:         []string{"SEA", "LAX", "JFK"},
+			to:           []string{"SFO", "LAX", "JFK"},
+			wantAdded:    []models.TicketPair{{"SFO", "LAX"}},
+			wantRemoved:  []models.TicketPair{{"SEA", "LAX"}},
+			wantAirports: [2][]string{{"SFO"}, {"SEA"}},
+			wantOrigin:   &models.AirportChange{From: "SEA", To: "SFO"},
+		},
+		{
+			name:         "flight taken twice, then once",
+			from:         []string{"SFO", "LAX", "SFO", "LAX"},
+			to:           []string{"SFO", "LAX"},
+			wantRemoved:  []models.TicketPair{{"LAX", "SFO"}, {"SFO", "LAX"}},
+			wantAirports: [2][]string{{}, {}},
+		},
+		{
+			name:      "two reroutes and a new destination",
+			from:      []string{"SFO", "LAX", "JFK", "BOS", "MIA"},
+			to:        []string{"SFO", "DEN", "JFK", "DCA", "MIA", "ATL"},
+			wantAdded: []models.TicketPair{{"MIA", "ATL"}},
+			wantRerouted: []models.Reroute{
+				{From: []string{"SFO", "LAX", "JFK"}, To: []string{"SFO", "DEN", "JFK"}},
+				{From: []string{"JFK", "BOS", "MIA"}, To: []string{"JFK", "DCA", "MIA"}},
+			},
+			wantAirports:    [2][]string{{"DEN", "DCA", "ATL"}, {"LAX", "BOS"}},
+			wantDestination: &models.AirportChange{From: "MIA", To: "ATL"},
+		},
+		{
+			name:            "every flight replaced",
+			from:            []string{"SFO", "LAX"},
+			to:              []string{"JFK", "BOS"},
+			wantAdded:       []models.TicketPair{{"JFK", "BOS"}},
+			wantRemoved:     []models.TicketPair{{"SFO", "LAX"}},
+			wantAirports:    [2][]string{{"JFK", "BOS"}, {"SFO", "LAX"}},
+			wantOrigin:      &models.AirportChange{From: "SFO", To: "JFK"},
+			wantDestination: &models.AirportChange{From: "LAX", To: "BOS"},
+		},
+	}
+
+	// nonNil returns s, or an empty slice for nil, as diffs never carry nil lists
+	nonNil := func(s []models.TicketPair) []models.TicketPair {
+		if s == nil {
+			return []models.TicketPair{}
+		}
+		return s
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffItineraries(tt.from, tt.to)
+
+			if !reflect.DeepEqual(diff.Added, nonNil(tt.wantAdded)) {
+				t.Errorf("Added = %v, want %v", diff.Added, tt.wantAdded)
+			}
+			if !reflect.DeepEqual(diff.Removed, nonNil(tt.wantRemoved)) {
+				t.Errorf("Removed = %v, want %v", diff.Removed, tt.wantRemoved)
+			}
+			if len(diff.Rerouted) != len(tt.wantRerouted) || len(tt.wantRerouted) > 0 && !reflect.DeepEqual(diff.Rerouted, tt.wantRerouted) {
+				t.Errorf("Rerouted = %v, want %v", diff.Rerouted, tt.wantRerouted)
+			}
+			wantAdded, wantRemoved := tt.wantAirports[0], tt.wantAirports[1]
+			if len(diff.AddedAirports) != len(wantAdded) || len(wantAdded) > 0 && !reflect.DeepEqual(diff.AddedAirports, wantAdded) {
+				t.Errorf("AddedAirports = %v, want %v", diff.AddedAirports, wantAdded)
+			}
+			if len(diff.RemovedAirports) != len(wantRemoved) || len(wantRemoved) > 0 && !reflect.DeepEqual(diff.RemovedAirports, wantRemoved) {
+				t.Errorf("RemovedAirports = %v, want %v", diff.RemovedAirports, wantRemoved)
+			}
+			if !reflect.DeepEqual(diff.Origin, tt.wantOrigin) {
+				t.Errorf("Origin = %+v, want %+v", diff.Origin, tt.wantOrigin)
+			}
+			if !reflect.DeepEqual(diff.Destination, tt.wantDestination) {
+				t.Errorf("Destination = %+v, want %+v", diff.Destination, tt.wantDestination)
+			}
+		})
+	}
+}
+
+func TestItineraryServiceDiff(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	diff, err := service.Diff(ctx, &models.ItineraryDiffRequest{
+		From: models.ItineraryRequest{Tickets: []models.TicketPair{{"LAX", "JFK"}, {"SFO", "LAX"}}},
+		To:   models.ItineraryRequest{Tickets: []models.TicketPair{{"SFO", "LAX"}, {"LAX", "BOS"}}},
+	})
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if !reflect.DeepEqual(diff.From, []string{"SFO", "LAX", "JFK"}) || !reflect.DeepEqual(diff.To, []string{"SFO", "LAX", "BOS"}) {
+		t.Errorf("Diff() itineraries = %v, %v", diff.From, diff.To)
+	}
+	if !reflect.DeepEqual(diff.Destination, &models.AirportChange{From: "JFK", To: "BOS"}) {
+		t.Errorf("Diff() destination = %+v", diff.Destination)
+	}
+
+	_, err = service.Diff(ctx, &models.ItineraryDiffRequest{
+		From: models.ItineraryRequest{Tickets: []models.TicketPair{{"SFO", "LAX"}}},
+		To:   models.ItineraryRequest{Tickets: []models.TicketPair{{"SFO", "LAX"}, {"SFO", "JFK"}}},
+	})
+	if ErrorCode(err) != "duplicate_source" {
+		t.Errorf("Diff(invalid tickets) error = %v, want duplicate_source", err)
+	}
+}
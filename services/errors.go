@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"flight-itinerary-api/models"
+)
+
+// ErrorKind classifies service failures independently of the transport reporting them
+type ErrorKind int
+
+// Error kinds
+const (
+	KindInternal ErrorKind = iota
+	KindInvalidInput
+	KindNotFound
+	KindConflict
+	KindUnavailable
+	KindTimeout
+	KindCancelled
+	KindUnprocessable
+	KindPreconditionFailed
+)
+
+// Codes of failures that are not service errors
+const (
+	codeInternal  = "internal_error"
+	codeCancelled = "request_cancelled"
+)
+
+// Error is a service failure with a stable code clients can rely on
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+}
+
+// Error returns the human readable message
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewError creates a service error of the given kind
+func NewError(kind ErrorKind, code, message string) error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// ErrorKindOf classifies any error returned by the service. Validation errors are invalid
+// input, an expired deadline is a timeout, and unknown errors are internal.
+func ErrorKindOf(err error) ErrorKind {
+	var (
+		serviceErr    *Error
+		validationErr *models.ValidationError
+	)
+	switch {
+	case errors.As(err, &serviceErr):
+		return serviceErr.Kind
+	case errors.As(err, &validationErr):
+		return KindInvalidInput
+	case errors.Is(err, context.DeadlineExceeded):
+		return KindTimeout
+	case errors.Is(err, context.Canceled):
+		return KindCancelled
+	default:
+		return KindInternal
+	}
+}
+
+// ErrorCode returns the stable code identifying any error returned by the service
+func ErrorCode(err error) string {
+	var (
+		serviceErr    *Error
+		validationErr *models.ValidationError
+	)
+	switch {
+	case errors.As(err, &serviceErr):
+		return serviceErr.Code
+	case errors.As(err, &validationErr):
+		return validationErr.Code
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorCode(ErrProcessingTimeout)
+	case errors.Is(err, context.Canceled):
+		return codeCancelled
+	default:
+		return codeInternal
+	}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"flight-itinerary-api/models"
+)
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantKind ErrorKind
+		wantCode string
+	}{
+		{"graph error", errDisconnectedRoute, KindInvalidInput, "disconnected_route"},
+		{"validation error", models.ErrInvalidFormat, KindInvalidInput, "invalid_format"},
+		{"wrapped overload", fmt.Errorf("%w: pool closed", ErrOverloaded), KindUnavailable, "overloaded"},
+		{"processing timeout", ErrProcessingTimeout, KindTimeout, "processing_timeout"},
+		{"bare deadline", context.DeadlineExceeded, KindTimeout, "processing_timeout"},
+		{"cancelled", context.Canceled, KindCancelled, "request_cancelled"},
+		{"panic", &PanicError{Value: "boom"}, KindInternal, "internal_error"},
+		{"unknown", errors.New("boom"), KindInternal, "internal_error"},
+		{"job not found", ErrJobNotFound, KindNotFound, "job_not_found"},
+		{"job finished", ErrJobFinished, KindConflict, "job_finished"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorKindOf(tt.err); got != tt.wantKind {
+				t.Errorf("ErrorKindOf() = %v, want %v", got, tt.wantKind)
+			}
+			if got := ErrorCode(tt.err); got != tt.wantCode {
+				t.Errorf("ErrorCode() = %q, want %q", got, tt.wantCode)
+			}
+		})
+	}
+}
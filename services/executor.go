@@ -0,0 +1,90 @@
+package services
+
+import (
+	"sync/atomic"
+
+	"github.com/panjf2000/ants/v2"
+
+	"flight-itinerary-api/config"
+)
+
+// ErrExecutorClosed is returned when a task is submitted to a released executor
+var ErrExecutorClosed = NewError(KindUnavailable, "shutting_down", "executor has been released")
+
+// Executor runs reconstruction tasks. Submit may block until the task can be started.
+type Executor interface {
+	Submit(task func()) error
+	Release()
+}
+
+// NewExecutor creates the executor selected by the worker pool configuration, defaulting to
+// an ants worker pool
+func NewExecutor(cfg *config.WorkerPoolConfig) Executor {
+	switch cfg.Executor {
+	case config.ExecutorSemaphore:
+		return newSemaphoreExecutor(cfg.WorkerCount)
+	case config.ExecutorInline:
+		return newInlineExecutor()
+	default:
+		pool, _ := ants.NewPool(cfg.WorkerCount)
+		return pool
+	}
+}
+
+// semaphoreExecutor starts a new goroutine per task, bounding the number of running tasks
+type semaphoreExecutor struct {
+	sem    chan struct{}
+	closed atomic.Bool
+}
+
+// newSemaphoreExecutor creates an executor running at most size tasks at once
+func newSemaphoreExecutor(size int) *semaphoreExecutor {
+	return &semaphoreExecutor{
+		sem: make(chan struct{}, max(size, 1)),
+	}
+}
+
+// Submit waits for a free slot and runs task on its own goroutine
+func (e *semaphoreExecutor) Submit(task func()) error {
+	if e.closed.Load() {
+		return ErrExecutorClosed
+	}
+
+	e.sem <- struct{}{}
+	go func() {
+		defer func() { <-e.sem }()
+		task()
+	}()
+	return nil
+}
+
+// Release stops accepting tasks; running tasks are left to finish
+func (e *semaphoreExecutor) Release() {
+	e.closed.Store(true)
+}
+
+// inlineExecutor runs every task in the submitting goroutine, avoiding the hand-off to a
+// worker entirely. Concurrency is then bounded by the callers alone.
+type inlineExecutor struct {
+	closed atomic.Bool
+}
+
+// newInlineExecutor creates an executor running tasks in the caller's goroutine
+func newInlineExecutor() *inlineExecutor {
+	return &inlineExecutor{}
+}
+
+// Submit runs task before returning
+func (e *inlineExecutor) Submit(task func()) error {
+	if e.closed.Load() {
+		return ErrExecutorClosed
+	}
+
+	task()
+	return nil
+}
+
+// Release stops accepting tasks
+func (e *inlineExecutor) Release() {
+	e.closed.Store(true)
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/models"
+)
+
+var executors = []string{config.ExecutorAnts, config.ExecutorSemaphore, config.ExecutorInline}
+
+func TestExecutor(t *testing.T) {
+	for _, name := range executors {
+		t.Run(name, func(t *testing.T) {
+			executor := NewExecutor(&config.WorkerPoolConfig{WorkerCount: 2, Executor: name})
+
+			var (
+				wg      sync.WaitGroup
+				running atomic.Int32
+				peak    atomic.Int32
+			)
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				err := executor.Submit(func() {
+					defer wg.Done()
+					current := running.Add(1)
+					defer running.Add(-1)
+					for {
+						old := peak.Load()
+						if current <= old || peak.CompareAndSwap(old, current) {
+							break
+						}
+					}
+				})
+				if err != nil {
+					t.Fatalf("Submit() error = %v", err)
+				}
+			}
+			wg.Wait()
+
+			if got := peak.Load(); got > 2 {
+				t.Errorf("%d tasks ran at once, want at most 2", got)
+			}
+
+			executor.Release()
+			if err := executor.Submit(func() {}); err == nil {
+				t.Error("Submit() after Release() succeeded")
+			}
+		})
+	}
+}
+
+func TestReconstructItineraryExecutors(t *testing.T) {
+	want := []string{"AAA", "AAB", "AAC", "AAD"}
+
+	for _, name := range executors {
+		t.Run(name, func(t *testing.T) {
+			cfg := &config.AppConfig{
+				WorkerPool: config.WorkerPoolConfig{
+					WorkerCount: 2,
+					Executor:    name,
+				},
+				Stream: config.StreamConfig{
+					MaxInFlight: 2,
+				},
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			service := NewItineraryService(ctx, cfg)
+
+			got, err := service.ReconstructItinerary(ctx, chainRequest(3))
+			if err != nil {
+				t.Fatalf("ReconstructItinerary() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ReconstructItinerary() = %v, want %v", got, want)
+			}
+
+			results := service.ReconstructBatch(ctx, []models.ItineraryRequest{*chainRequest(3), *chainRequest(3)})
+			for i, result := range results {
+				if result.Err != nil || !reflect.DeepEqual(result.Itinerary, want) {
+					t.Errorf("ReconstructBatch()[%d] = %v, %v, want %v", i, result.Itinerary, result.Err, want)
+				}
+			}
+
+			requests := make(chan StreamRequest, 4)
+			for i := 0; i < 4; i++ {
+				requests <- StreamRequest{Index: i, Request: *chainRequest(3)}
+			}
+			close(requests)
+
+			emitted := 0
+			err = service.ReconstructStream(ctx, requests, func(result StreamResult) error {
+				if result.Err != nil || !reflect.DeepEqual(result.Itinerary, want) {
+					t.Errorf("ReconstructStream() item %d = %v, %v, want %v", result.Index, result.Itinerary, result.Err, want)
+				}
+				emitted++
+				return nil
+			})
+			if err != nil || emitted != 4 {
+				t.Errorf("ReconstructStream() = %v after %d results, want 4 results", err, emitted)
+			}
+		})
+	}
+}
+
+func BenchmarkExecutors(b *testing.B) {
+	for _, name := range executors {
+		for _, n := range []int{2, 100, 10000} {
+			b.Run(fmt.Sprintf("executor=%s/tickets=%d", name, n), func(b *testing.B) {
+				cfg := &config.AppConfig{
+					WorkerPool: config.WorkerPoolConfig{
+						WorkerCount: 64,
+						Executor:    name,
+					},
+				}
+
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				service := NewItineraryService(ctx, cfg)
+				request := chainRequest(n)
+
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						if _, err := service.ReconstructItinerary(ctx, request); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			})
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import "context"
+
+// clientKey is the context key under which the client identity is stored
+type clientKey struct{}
+
+// WithClient returns a context whose reconstructions are queued fairly on behalf of client,
+// typically its API key or IP address
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFromContext returns the client identity carried by ctx, or the empty string
+func ClientFromContext(ctx context.Context) string {
+	client, _ := ctx.Value(clientKey{}).(string)
+	return client
+}
+
+// fairQueue orders waiting callers with weighted fair queuing: every client has its own
+// FIFO queue, each caller is tagged with a virtual start and finish time advanced by the
+// inverse of its client's weight, and the caller with the earliest finish tag is served next.
+// A client with weight w is therefore served w times as often as a client with weight 1 while
+// both have callers waiting, however many callers each of them queues. Clients that were idle
+// resume at the current virtual time rather than with banked credit.
+type fairQueue struct {
+	clients map[string]*clientQueue
+	vtime   float64
+	seq     uint64
+	len     int
+}
+
+// clientQueue holds the waiting callers of one client, in arrival order
+type clientQueue struct {
+	waiters    []*waiter
+	lastFinish float64
+}
+
+// newFairQueue creates an empty fair queue
+func newFairQueue() *fairQueue {
+	return &fairQueue{
+		clients: make(map[string]*clientQueue),
+	}
+}
+
+// push tags w for client with the given weight and appends it to the client's queue
+func (q *fairQueue) push(w *waiter, client string, weight int) {
+	cq, exists := q.clients[client]
+	if !exists {
+		cq = &clientQueue{}
+		q.clients[client] = cq
+	}
+
+	w.client = client
+	w.start = max(q.vtime, cq.lastFinish)
+	w.finish = w.start + 1/float64(max(weight, 1))
+	w.seq = q.seq
+	q.seq++
+
+	cq.lastFinish = w.finish
+	cq.waiters = append(cq.waiters, w)
+	q.len++
+}
+
+// pop removes and returns the caller to serve next, or nil when the queue is empty
+func (q *fairQueue) pop() *waiter {
+	var next *waiter
+	for client, cq := range q.clients {
+		if len(cq.waiters) == 0 {
+			// Forget clients with neither waiting callers nor pending virtual time
+			if cq.lastFinish <= q.vtime {
+				delete(q.clients, client)
+			}
+			continue
+		}
+		head := cq.waiters[0]
+		if next == nil || head.finish < next.finish || (head.finish == next.finish && head.seq < next.seq) {
+			next = head
+		}
+	}
+	if next == nil {
+		return nil
+	}
+
+	q.vtime = next.start
+	q.remove(next)
+	return next
+}
+
+// remove takes w out of its client's queue, reporting whether it was queued
+func (q *fairQueue) remove(w *waiter) bool {
+	cq, exists := q.clients[w.client]
+	if !exists {
+		return false
+	}
+	for i, queued := range cq.waiters {
+		if queued == w {
+			cq.waiters = append(cq.waiters[:i], cq.waiters[i+1:]...)
+			q.len--
+			return true
+		}
+	}
+	return false
+}
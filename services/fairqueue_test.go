@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/metrics"
+)
+
+func TestFairQueueWeights(t *testing.T) {
+	q := newFairQueue()
+
+	// Both clients keep a backlog; "heavy" has three times the weight of "light"
+	for i := 0; i < 12; i++ {
+		q.push(&waiter{}, "heavy", 3)
+		q.push(&waiter{}, "light", 1)
+	}
+
+	served := map[string]int{}
+	for i := 0; i < 8; i++ {
+		served[q.pop().client]++
+	}
+	if served["heavy"] != 6 || served["light"] != 2 {
+		t.Errorf("served %v in the first 8 pops, want heavy:6 light:2", served)
+	}
+
+	for q.pop() != nil {
+	}
+	if q.len != 0 {
+		t.Errorf("len = %d after draining, want 0", q.len)
+	}
+}
+
+func TestFairQueueFlooding(t *testing.T) {
+	q := newFairQueue()
+
+	// A client that queued a long backlog does not delay a newcomer behind all of it
+	for i := 0; i < 100; i++ {
+		q.push(&waiter{}, "flooder", 1)
+	}
+	q.pop()
+	q.push(&waiter{}, "newcomer", 1)
+
+	if first, second := q.pop().client, q.pop().client; first != "newcomer" && second != "newcomer" {
+		t.Errorf("newcomer not served within two pops, got %s then %s", first, second)
+	}
+}
+
+func TestFairQueueRemove(t *testing.T) {
+	q := newFairQueue()
+	gone := &waiter{}
+	q.push(gone, "a", 1)
+	q.push(&waiter{}, "b", 1)
+
+	if !q.remove(gone) {
+		t.Fatal("remove() = false for a queued waiter")
+	}
+	if q.remove(gone) {
+		t.Error("remove() = true for a waiter removed twice")
+	}
+	if next := q.pop(); next == gone || next.client != "b" {
+		t.Errorf("pop() returned %+v, want the waiter of b", next)
+	}
+	if next := q.pop(); next != nil {
+		t.Errorf("pop() = %+v on an empty queue, want nil", next)
+	}
+}
+
+func TestAdmissionFairness(t *testing.T) {
+	a := newAdmission(1, &config.AdmissionConfig{}, metrics.NewRegistry(), poolSmall)
+	if err := a.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	// One client floods the queue before another sends a single request
+	order := make(chan string, 6)
+	for _, client := range []string{"flooder", "flooder", "flooder", "flooder", "flooder", "other"} {
+		want := queued(a) + 1
+		go func() {
+			if err := a.acquire(WithClient(context.Background(), client)); err != nil {
+				t.Errorf("acquire(%s) error = %v", client, err)
+				return
+			}
+			order <- client
+			a.release()
+		}()
+		for queued(a) < want {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	a.release()
+	if first, second := <-order, <-order; first != "other" && second != "other" {
+		t.Errorf("other client served after %s and %s, want within the first two", first, second)
+	}
+	for i := 0; i < 4; i++ {
+		<-order
+	}
+}
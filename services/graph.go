@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// airportSpace is the number of distinct 3-letter uppercase airport codes (26³)
+const airportSpace = 26 * 26 * 26
+
+// maxPooledNodes bounds the node capacity of graphs returned to the pool, so a single huge
+// request does not pin its buffers for the lifetime of the process
+const maxPooledNodes = 1 << 16
+
+// noNode marks the absence of a successor
+const noNode int32 = -1
+
+// cancelCheckInterval is the number of loop iterations between two context checks, which
+// keeps cancellation responsive without paying for a check on every airport
+const cancelCheckInterval = 1 << 12
+
+// Reconstruction errors
+var (
+	errDuplicateSource   = NewError(KindInvalidInput, "duplicate_source", "invalid tickets: multiple flights from same source")
+	errMultipleStarts    = NewError(KindInvalidInput, "multiple_starts", "invalid tickets: multiple starting points found")
+	errNoStart           = NewError(KindInvalidInput, "no_start", "invalid tickets: no starting point found")
+	errDisconnectedRoute = NewError(KindInvalidInput, "disconnected_route", "invalid tickets: disconnected route")
+)
+
+// routeGraph is a reusable directed graph of airports in which every airport has at most one
+// outgoing flight. Airports are numbered densely in insertion order; 3-letter uppercase codes
+// are interned through a fixed array and any other code falls back to a map, so the common
+// case hashes no strings and, once pooled, allocates nothing but the resulting itinerary.
+type routeGraph struct {
+	// slot maps an interned airport code to its node index plus one; zero means absent
+	slot  [airportSpace]int32
+	other map[string]int32
+
+	names    []string
+	next     []int32
+	inDegree []int32
+	tickets  int
+}
+
+// graphPool recycles graphs and their scratch buffers across requests
+var graphPool = sync.Pool{
+	New: func() interface{} {
+		return &routeGraph{}
+	},
+}
+
+// acquireRouteGraph returns an empty graph from the pool
+func acquireRouteGraph() *routeGraph {
+	return graphPool.Get().(*routeGraph)
+}
+
+// release resets the graph and returns it to the pool; the graph must not be used afterwards
+func (g *routeGraph) release() {
+	if cap(g.names) > maxPooledNodes {
+		return
+	}
+
+	// Only clear the slots that were used, rather than the whole array
+	for _, name := range g.names {
+		if id, ok := internAirport(name); ok {
+			g.slot[id] = 0
+		}
+	}
+	clear(g.other)
+
+	g.names = g.names[:0]
+	g.next = g.next[:0]
+	g.inDegree = g.inDegree[:0]
+	g.tickets = 0
+
+	graphPool.Put(g)
+}
+
+// internAirport maps a 3-letter uppercase airport code to a dense integer ID
+func internAirport(code string) (int32, bool) {
+	if len(code) != 3 {
+		return 0, false
+	}
+
+	var id int32
+	for i := 0; i < 3; i++ {
+		c := code[i]
+		if c < 'A' || c > 'Z' {
+			return 0, false
+		}
+		id = id*26 + int32(c-'A')
+	}
+	return id, true
+}
+
+// node returns the node index of an airport, adding the airport if it is new
+func (g *routeGraph) node(code string) int32 {
+	if id, ok := internAirport(code); ok {
+		if s := g.slot[id]; s != 0 {
+			return s - 1
+		}
+		n := g.appendNode(code)
+		g.slot[id] = n + 1
+		return n
+	}
+
+	if n, exists := g.other[code]; exists {
+		return n
+	}
+	if g.other == nil {
+		g.other = make(map[string]int32)
+	}
+	n := g.appendNode(code)
+	g.other[code] = n
+	return n
+}
+
+// appendNode adds a new airport without incoming or outgoing flights
+func (g *routeGraph) appendNode(code string) int32 {
+	g.names = append(g.names, code)
+	g.next = append(g.next, noNode)
+	g.inDegree = append(g.inDegree, 0)
+	return int32(len(g.names) - 1)
+}
+
+// addTicket inserts a flight from src to dst
+func (g *routeGraph) addTicket(src, dst string) error {
+	from := g.node(src)
+
+	// Check for duplicate flights from same source
+	if g.next[from] != noNode {
+		return errDuplicateSource
+	}
+
+	to := g.node(dst)
+	g.next[from] = to
+	g.inDegree[to]++
+	g.tickets++
+	return nil
+}
+
+// airports returns the number of distinct airports in the graph
+func (g *routeGraph) airports() int {
+	return len(g.names)
+}
+
+// findStarts returns a node without incoming flights and the number of such nodes
+func (g *routeGraph) findStarts(ctx context.Context) (int32, int, error) {
+	start, starts := noNode, 0
+	for n, degree := range g.inDegree {
+		if err := checkCancelled(ctx, n); err != nil {
+			return noNode, 0, err
+		}
+		if degree == 0 {
+			start = int32(n)
+			starts++
+		}
+	}
+	return start, starts, nil
+}
+
+// walk follows the flights from start and returns the visited airports, failing unless the
+// route uses every ticket
+func (g *routeGraph) walk(ctx context.Context, start int32) ([]string, error) {
+	itinerary := make([]string, 0, g.tickets+1)
+	current := start
+
+	for len(itinerary) <= g.tickets {
+		if err := checkCancelled(ctx, len(itinerary)); err != nil {
+			return nil, err
+		}
+		itinerary = append(itinerary, g.names[current])
+
+		current = g.next[current]
+		if current == noNode {
+			break // We've reached the final destination
+		}
+	}
+
+	// Verify we used all tickets
+	if len(itinerary)-1 != g.tickets {
+		return nil, errDisconnectedRoute
+	}
+
+	return itinerary, nil
+}
+
+// checkCancelled returns the context's error every cancelCheckInterval iterations
+func checkCancelled(ctx context.Context, iteration int) error {
+	if iteration%cancelCheckInterval != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"flight-itinerary-api/models"
+)
+
+func TestInternAirport(t *testing.T) {
+	tests := []struct {
+		code   string
+		wantID int32
+		wantOK bool
+	}{
+		{code: "AAA", wantID: 0, wantOK: true},
+		{code: "AAB", wantID: 1, wantOK: true},
+		{code: "ZZZ", wantID: airportSpace - 1, wantOK: true},
+		{code: "sfo", wantOK: false},
+		{code: "SF0", wantOK: false},
+		{code: "SFOO", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			id, ok := internAirport(tt.code)
+			if ok != tt.wantOK || (ok && id != tt.wantID) {
+				t.Errorf("internAirport(%q) = %d, %v, want %d, %v", tt.code, id, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestProcessItineraryMixedCodes(t *testing.T) {
+	// Codes that cannot be interned take the map fallback within the same graph
+	request := &models.ItineraryRequest{
+		Tickets: []models.TicketPair{{"lax", "JFK"}, {"SFO", "lax"}, {"JFK", "B2B"}},
+	}
+
+	got, err := (&ItineraryService{}).processItinerary(context.Background(), request, func(Stage, int) {})
+	if err != nil {
+		t.Fatalf("processItinerary() error = %v", err)
+	}
+	if want := []string{"SFO", "lax", "JFK", "B2B"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("processItinerary() = %v, want %v", got, want)
+	}
+}
+
+func TestRouteGraphRelease(t *testing.T) {
+	graph := acquireRouteGraph()
+	for _, ticket := range [][2]string{{"SFO", "LAX"}, {"LAX", "jfk"}} {
+		if err := graph.addTicket(ticket[0], ticket[1]); err != nil {
+			t.Fatalf("addTicket() error = %v", err)
+		}
+	}
+	graph.release()
+
+	// A released graph must come back empty, whether or not it is the same instance
+	graph = acquireRouteGraph()
+	defer graph.release()
+
+	if graph.airports() != 0 || graph.tickets != 0 || len(graph.other) != 0 {
+		t.Fatalf("acquired graph is not empty: %d airports, %d tickets", graph.airports(), graph.tickets)
+	}
+	for id, slot := range graph.slot {
+		if slot != 0 {
+			t.Fatalf("slot %d not cleared", id)
+		}
+	}
+}
+
+func TestRouteGraphCancelled(t *testing.T) {
+	graph := acquireRouteGraph()
+	defer graph.release()
+	for _, ticket := range chainRequest(3 * cancelCheckInterval).Tickets {
+		if err := graph.addTicket(ticket[0], ticket[1]); err != nil {
+			t.Fatalf("addTicket() error = %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, _, err := graph.findStarts(ctx); err != context.Canceled {
+		t.Errorf("findStarts() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := graph.walk(ctx, 0); err != context.Canceled {
+		t.Errorf("walk() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := graph.walkParallel(ctx, 0, 4); err != context.Canceled {
+		t.Errorf("walkParallel() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+// chainRequest builds a linear route of n tickets over 3-letter codes
+func chainRequest(n int) *models.ItineraryRequest {
+	code := func(i int) string {
+		return fmt.Sprintf("%c%c%c", 'A'+i/676%26, 'A'+i/26%26, 'A'+i%26)
+	}
+
+	tickets := make([]models.TicketPair, n)
+	for i := 0; i < n; i++ {
+		// Insert in reverse order so the walk cannot rely on input order
+		tickets[n-1-i] = models.TicketPair{code(i), code(i + 1)}
+	}
+	return &models.ItineraryRequest{Tickets: tickets}
+}
+
+func BenchmarkProcessItinerary(b *testing.B) {
+	service := &ItineraryService{}
+	for _, n := range []int{2, 100, 10000} {
+		request := chainRequest(n)
+		b.Run(fmt.Sprintf("tickets=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := service.processItinerary(context.Background(), request, func(Stage, int) {}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -3,123 +3,362 @@ package services
 import (
 	"context"
 	"errors"
-	"sync"
+	"time"
 
-	"github.com/panjf2000/ants/v2"
+	"go.uber.org/zap"
 
 	"flight-itinerary-api/config"
+	"flight-itinerary-api/metrics"
 	"flight-itinerary-api/models"
 )
 
+// defaultStreamMaxLineBytes bounds a single streamed ticket set when no limit is configured
+const defaultStreamMaxLineBytes = 1 << 20
+
+// ErrProcessingTimeout is returned when a reconstruction does not finish within the processing deadline
+var ErrProcessingTimeout = NewError(KindTimeout, "processing_timeout", "processing deadline exceeded")
+
 // ItineraryService handles the business logic for processing flight tickets
 type ItineraryService struct {
-	pool *ants.Pool
+	small          *workerPool
+	large          *workerPool
+	largeThreshold int
+	metrics        *metrics.Registry
+	logger         *zap.Logger
+	panics         metrics.Counter
+	maxBatchSize   int
+	streamInFlight int
+	streamMaxLine  int
+
+	parallelThreshold int
+	parallelWorkers   int
+	processingTimeout time.Duration
+
+	cache     *resultCache
+	coalescer *coalescer
+}
+
+// BatchResult holds the outcome of a single ticket set processed as part of a batch
+type BatchResult struct {
+	Itinerary []string
+	Err       error
+}
+
+// Option customises an ItineraryService
+type Option func(*ItineraryService)
+
+// WithMetrics makes the service record its metrics in registry instead of a private one
+func WithMetrics(registry *metrics.Registry) Option {
+	return func(s *ItineraryService) {
+		s.metrics = registry
+	}
+}
+
+// WithLogger makes the service log unexpected failures to logger
+func WithLogger(logger *zap.Logger) Option {
+	return func(s *ItineraryService) {
+		s.logger = logger
+	}
 }
 
 // NewItineraryService creates a new instance of ItineraryService
-func NewItineraryService(ctx context.Context, cfg *config.AppConfig) *ItineraryService {
-	pool, _ := ants.NewPool(cfg.WorkerPool.WorkerCount)
+func NewItineraryService(ctx context.Context, cfg *config.AppConfig, opts ...Option) *ItineraryService {
+	// Default the stream window to one ticket set per worker
+	streamInFlight := cfg.Stream.MaxInFlight
+	if streamInFlight <= 0 {
+		streamInFlight = cfg.WorkerPool.WorkerCount
+	}
 
-	// Start a goroutine to watch for context cancellation
-	go func() {
-		<-ctx.Done()
-		pool.Release()
-	}()
+	streamMaxLine := cfg.Stream.MaxLineBytes
+	if streamMaxLine <= 0 {
+		streamMaxLine = defaultStreamMaxLineBytes
+	}
 
-	return &ItineraryService{
-		pool: pool,
+	s := &ItineraryService{
+		largeThreshold: cfg.LargePool.Threshold,
+		metrics:        metrics.NewRegistry(),
+		logger:         zap.NewNop(),
+		maxBatchSize:   cfg.Batch.MaxItems,
+		streamInFlight: streamInFlight,
+		streamMaxLine:  streamMaxLine,
+
+		parallelThreshold: cfg.Reconstruction.ParallelThreshold,
+		parallelWorkers:   cfg.Reconstruction.ParallelWorkers,
+		processingTimeout: cfg.Reconstruction.ProcessingTimeout,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	s.panics = s.metrics.Counter("itinerary_task_panics_total", "Reconstruction tasks that panicked.")
+	s.small = newWorkerPool(ctx, poolSmall, &cfg.WorkerPool, &cfg.Admission, s.metrics)
+	s.large = newLargePool(ctx, cfg, s.metrics)
+	s.cache = newResultCache(&cfg.Cache, s.metrics)
+	s.coalescer = newCoalescer(&cfg.Coalescing, s.metrics)
+
+	return s
 }
 
-// ReconstructItinerary processes the flight tickets and returns an ordered itinerary
+// MaxBatchSize returns the maximum number of ticket sets accepted in a single batch
+func (s *ItineraryService) MaxBatchSize() int {
+	return s.maxBatchSize
+}
+
+// Metrics returns the registry the service records its metrics in
+func (s *ItineraryService) Metrics() *metrics.Registry {
+	return s.metrics
+}
+
+// RetryAfter returns how long callers shed with ErrOverloaded should wait before retrying
+func (s *ItineraryService) RetryAfter() time.Duration {
+	return s.small.admission.retryAfter
+}
+
+// MaxStreamLineBytes returns the maximum size of a single ticket set line in a stream
+func (s *ItineraryService) MaxStreamLineBytes() int {
+	return s.streamMaxLine
+}
+
+// ReconstructItinerary processes the flight tickets and returns an ordered itinerary,
+// serving repeated ticket sets from the result cache and sharing the reconstruction of
+// identical ticket sets in flight
 func (s *ItineraryService) ReconstructItinerary(ctx context.Context, request *models.ItineraryRequest) ([]string, error) {
-	var (
-		result []string
-		err    error
-		wg     sync.WaitGroup
-	)
-
-	wg.Add(1)
-	submitErr := s.pool.Submit(func() {
-		defer wg.Done()
-		result, err = processItinerary(request)
+	tickets := len(request.Tickets)
+	key := s.keyFor(request.Tickets)
+	cached, store := s.lookup(ctx, tickets, key)
+	if cached != nil {
+		return cached.itinerary, cached.err
+	}
+
+	ctx, cancel := s.withDeadline(ctx)
+	defer cancel()
+
+	call, shared := s.lead(ctx, tickets, key)
+	if shared != nil {
+		return deadlineError(shared.itinerary, shared.err)
+	}
+
+	report := progressReporter(ctx, 0)
+
+	resultCh, err := s.submit(ctx, tickets, call, func() ([]string, error) {
+		return s.processItinerary(ctx, request, report)
+	})
+	if err != nil {
+		return deadlineError(nil, err)
+	}
+
+	itinerary, err := deadlineError(await(ctx, resultCh))
+	store(itinerary, err)
+	return itinerary, err
+}
+
+// TicketSource feeds tickets to a reconstruction by calling visit for each ticket as it is
+// decoded, stopping at the first error visit returns
+type TicketSource func(visit func(src, dst string) error) error
+
+// ReconstructFromSource builds the flight graph directly from source in the calling
+// goroutine, so memory stays proportional to the graph rather than to the raw request,
+// then resolves the itinerary on the worker pool. Graph errors such as duplicate sources
+// stop decoding early. The processing deadline starts once the source is exhausted.
+// Ticket sets small enough to be cached or coalesced are also collected, so that once
+// decoded they can be served from the result cache or an identical reconstruction in flight.
+func (s *ItineraryService) ReconstructFromSource(ctx context.Context, source TicketSource) ([]string, error) {
+	var tickets []models.TicketPair
+	graph := acquireRouteGraph()
+	err := source(func(src, dst string) error {
+		if err := checkCancelled(ctx, graph.tickets); err != nil {
+			return err
+		}
+		if s.keyable(graph.tickets + 1) {
+			tickets = append(tickets, models.TicketPair{src, dst})
+		}
+		return graph.addTicket(src, dst)
 	})
+	if err != nil {
+		graph.release()
+		return nil, err
+	}
+
+	var key cacheKey
+	if s.keyable(graph.tickets) {
+		key = ticketSetKey(tickets)
+	}
+	cached, store := s.lookup(ctx, graph.tickets, key)
+	if cached != nil {
+		graph.release()
+		return cached.itinerary, cached.err
+	}
+
+	ctx, cancel := s.withDeadline(ctx)
+	defer cancel()
 
-	if submitErr != nil {
-		return nil, submitErr
+	call, shared := s.lead(ctx, graph.tickets, key)
+	if shared != nil {
+		graph.release()
+		return deadlineError(shared.itinerary, shared.err)
 	}
 
-	// Wait for completion or context cancellation
-	doneCh := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(doneCh)
-	}()
+	report := progressReporter(ctx, 0)
+	report(StageTicketsParsed, graph.tickets)
 
-	select {
-	case <-doneCh:
-		return result, err
-	case <-ctx.Done():
-		return nil, ctx.Err()
+	// The task owns the graph from here on and returns it to the pool once resolved;
+	// a skipped task simply leaves it to the garbage collector
+	resultCh, err := s.submit(ctx, graph.tickets, call, func() ([]string, error) {
+		defer graph.release()
+		return s.resolveItinerary(ctx, graph, report)
+	})
+	if err != nil {
+		graph.release()
+		return deadlineError(nil, err)
 	}
+
+	itinerary, err := deadlineError(await(ctx, resultCh))
+	store(itinerary, err)
+	return itinerary, err
 }
 
-// processItinerary handles the actual itinerary reconstruction logic
-func processItinerary(request *models.ItineraryRequest) ([]string, error) {
-	// Build graph representation of flights
-	graph := make(map[string]string)
-	inDegree := make(map[string]int)
+// ReconstructBatch processes independent ticket sets concurrently on the worker pool.
+// Results are returned in input order; a failing item never aborts the rest of the batch.
+// The processing deadline applies to the batch as a whole, and once an item has been shed
+// the remaining items are shed without waiting for a worker. Items found in the result
+// cache are served from it, even once the batch is being shed, and items identical to a
+// ticket set in flight, within the batch or not, share its reconstruction.
+func (s *ItineraryService) ReconstructBatch(ctx context.Context, requests []models.ItineraryRequest) []BatchResult {
+	// The cache status describes single ticket sets only
+	ctx = WithCacheStatus(ctx, nil)
+	ctx, cancel := s.withDeadline(ctx)
+	defer cancel()
+
+	results := make([]BatchResult, len(requests))
+	pending := make([]<-chan taskResult, len(requests))
+	followed := make([]*sharedCall, len(requests))
+	stores := make([]func([]string, error), len(requests))
+	shed := false
 
-	// Populate the graph and count incoming edges
-	for _, ticket := range request.Tickets {
-		src, dst := ticket[0], ticket[1]
+	for i := range requests {
+		if err := requests[i].Validate(); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		request := &requests[i]
+		tickets := len(request.Tickets)
+		key := s.keyFor(request.Tickets)
+		cached, store := s.lookup(ctx, tickets, key)
+		if cached != nil {
+			results[i] = BatchResult{Itinerary: cached.itinerary, Err: cached.err}
+			continue
+		}
 
-		// Check for duplicate flights from same source
-		if _, exists := graph[src]; exists {
-			return nil, errors.New("invalid tickets: multiple flights from same source")
+		call, leader := s.coalescer.join(tickets, key)
+		if !leader {
+			followed[i] = call
+			continue
 		}
 
-		graph[src] = dst
-		inDegree[dst]++
-		// Ensure source is in inDegree map
-		if _, exists := inDegree[src]; !exists {
-			inDegree[src] = 0
+		// Stop feeding the pool once the caller has gone away or the pool is saturated
+		if err := ctx.Err(); err != nil {
+			call.land(nil, err)
+			_, results[i].Err = deadlineError(nil, err)
+			continue
 		}
+		if shed {
+			call.land(nil, ErrOverloaded)
+			results[i].Err = ErrOverloaded
+			continue
+		}
+
+		report := progressReporter(ctx, i)
+		resultCh, err := s.submit(ctx, tickets, call, func() ([]string, error) {
+			return s.processItinerary(ctx, request, report)
+		})
+		if err != nil {
+			_, results[i].Err = deadlineError(nil, err)
+			shed = errors.Is(err, ErrOverloaded)
+			continue
+		}
+		pending[i] = resultCh
+		stores[i] = store
 	}
 
-	// Find starting airport (node with no incoming edges)
-	var start string
-	for airport, degree := range inDegree {
-		if degree == 0 {
-			if start != "" {
-				return nil, errors.New("invalid tickets: multiple starting points found")
+	// Collect in input order; tasks skipped after cancellation report promptly
+	for i := range requests {
+		switch {
+		case pending[i] != nil:
+			result := <-pending[i]
+			results[i].Itinerary, results[i].Err = deadlineError(result.itinerary, result.err)
+			stores[i](results[i].Itinerary, results[i].Err)
+		case followed[i] != nil:
+			result, shared := followed[i].wait(ctx)
+			if !shared {
+				// The reconstruction followed failed for reasons of its own
+				result.itinerary, result.err = s.ReconstructItinerary(ctx, &requests[i])
 			}
-			start = airport
+			results[i].Itinerary, results[i].Err = deadlineError(result.itinerary, result.err)
 		}
 	}
 
-	if start == "" {
-		return nil, errors.New("invalid tickets: no starting point found")
-	}
+	return results
+}
 
-	// Construct itinerary by following the graph
-	itinerary := make([]string, 0, len(request.Tickets)+1)
-	current := start
+// processItinerary handles the actual itinerary reconstruction logic, calling report as it
+// passes each reconstruction stage
+func (s *ItineraryService) processItinerary(ctx context.Context, request *models.ItineraryRequest, report func(Stage, int)) ([]string, error) {
+	report(StageTicketsParsed, len(request.Tickets))
 
-	for len(itinerary) <= len(request.Tickets) {
-		itinerary = append(itinerary, current)
+	// Build graph representation of flights
+	graph := acquireRouteGraph()
+	defer graph.release()
 
-		next, exists := graph[current]
-		if !exists {
-			break // We've reached the final destination
+	for i, ticket := range request.Tickets {
+		if err := checkCancelled(ctx, i); err != nil {
+			return nil, err
 		}
-		current = next
+		if err := graph.addTicket(ticket[0], ticket[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.resolveItinerary(ctx, graph, report)
+}
+
+// resolveItinerary orders the flights of a fully built graph. Routes of at least
+// parallelThreshold tickets are walked in parallel.
+func (s *ItineraryService) resolveItinerary(ctx context.Context, graph *routeGraph, report func(Stage, int)) ([]string, error) {
+	report(StageGraphBuilt, graph.airports())
+
+	// Find starting airports (nodes with no incoming edges); a valid route has exactly one
+	start, starts, err := graph.findStarts(ctx)
+	if err != nil {
+		return nil, err
 	}
+	report(StageComponentsFound, starts)
 
-	// Verify we used all tickets
-	if len(itinerary)-1 != len(request.Tickets) {
-		return nil, errors.New("invalid tickets: disconnected route")
+	if starts > 1 {
+		return nil, errMultipleStarts
 	}
+	if starts == 0 {
+		return nil, errNoStart
+	}
+
+	// Construct itinerary by following the graph
+	if s.parallelThreshold > 0 && graph.tickets >= s.parallelThreshold {
+		return graph.walkParallel(ctx, start, s.parallelWorkers)
+	}
+	return graph.walk(ctx, start)
+}
 
-	return itinerary, nil
+// withDeadline bounds ctx by the processing timeout, if one is configured
+func (s *ItineraryService) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.processingTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.processingTimeout)
+}
+
+// deadlineError reports an expired deadline as ErrProcessingTimeout, passing anything else through
+func deadlineError(itinerary []string, err error) ([]string, error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, ErrProcessingTimeout
+	}
+	return itinerary, err
 }
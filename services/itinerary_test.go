@@ -2,8 +2,15 @@ package services
 
 import (
 	"context"
+	"errors"
 	"reflect"
+	"runtime"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 
 	"flight-itinerary-api/config"
 	"flight-itinerary-api/models"
@@ -193,3 +200,371 @@ func TestReconstructItinerary(t *testing.T) {
 		})
 	}
 }
+
+func TestReconstructBatch(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 2,
+		},
+		Batch: config.BatchConfig{
+			MaxItems: 10,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	requests := []models.ItineraryRequest{
+		{Tickets: []models.TicketPair{{"LAX", "JFK"}, {"SFO", "LAX"}}},
+		{Tickets: []models.TicketPair{{"SFO", "LAX"}, {"JFK", "MCO"}}},
+		{Tickets: []models.TicketPair{}},
+		{Tickets: []models.TicketPair{{"ATL", "MCO"}, {"SFO", "ATL"}, {"MCO", "JFK"}}},
+	}
+
+	results := service.ReconstructBatch(context.Background(), requests)
+	if len(results) != len(requests) {
+		t.Fatalf("ReconstructBatch() returned %d results, want %d", len(results), len(requests))
+	}
+
+	wantItineraries := [][]string{
+		{"SFO", "LAX", "JFK"},
+		nil,
+		nil,
+		{"SFO", "ATL", "MCO", "JFK"},
+	}
+	wantErrs := []bool{false, true, true, false}
+
+	for i, result := range results {
+		if (result.Err != nil) != wantErrs[i] {
+			t.Errorf("item %d: error = %v, wantErr %v", i, result.Err, wantErrs[i])
+		}
+		if !reflect.DeepEqual(result.Itinerary, wantItineraries[i]) {
+			t.Errorf("item %d: itinerary = %v, want %v", i, result.Itinerary, wantItineraries[i])
+		}
+	}
+
+	// A cancelled context must not submit further work
+	cancelledCtx, cancelRequest := context.WithCancel(context.Background())
+	cancelRequest()
+	for i, result := range service.ReconstructBatch(cancelledCtx, requests[:1]) {
+		if result.Err != context.Canceled {
+			t.Errorf("item %d: error = %v, want %v", i, result.Err, context.Canceled)
+		}
+	}
+}
+
+func TestReconstructItineraryReportsProgress(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	type report struct {
+		stage Stage
+		count int
+	}
+	var reports []report
+	progressCtx := WithProgress(context.Background(), func(index int, stage Stage, count int) {
+		reports = append(reports, report{stage, count})
+	})
+
+	request := &models.ItineraryRequest{
+		Tickets: []models.TicketPair{{"LAX", "JFK"}, {"SFO", "LAX"}},
+	}
+	if _, err := service.ReconstructItinerary(progressCtx, request); err != nil {
+		t.Fatalf("ReconstructItinerary() error = %v", err)
+	}
+
+	want := []report{
+		{StageTicketsParsed, 2},
+		{StageGraphBuilt, 3},
+		{StageComponentsFound, 1},
+	}
+	if !reflect.DeepEqual(reports, want) {
+		t.Errorf("progress reports = %+v, want %+v", reports, want)
+	}
+}
+
+func TestReconstructFromSource(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	// source feeds the given tickets one by one, as a streaming decoder would
+	source := func(tickets ...[2]string) TicketSource {
+		return func(visit func(src, dst string) error) error {
+			for _, ticket := range tickets {
+				if err := visit(ticket[0], ticket[1]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	got, err := service.ReconstructFromSource(context.Background(), source([2]string{"LAX", "JFK"}, [2]string{"SFO", "LAX"}))
+	if err != nil {
+		t.Fatalf("ReconstructFromSource() error = %v", err)
+	}
+	if want := []string{"SFO", "LAX", "JFK"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReconstructFromSource() = %v, want %v", got, want)
+	}
+
+	// Graph errors surface while the source is still being read
+	_, err = service.ReconstructFromSource(context.Background(), source([2]string{"SFO", "LAX"}, [2]string{"SFO", "JFK"}))
+	if err != errDuplicateSource {
+		t.Errorf("ReconstructFromSource() error = %v, want %v", err, errDuplicateSource)
+	}
+}
+
+func TestReconstructItinerarySkipsCancelledWork(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	// Occupy the only worker
+	release := make(chan struct{})
+	if err := service.small.executor.Submit(func() { <-release }); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	var started atomic.Bool
+	requestCtx, cancelRequest := context.WithCancel(WithProgress(context.Background(), func(int, Stage, int) {
+		started.Store(true)
+	}))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.ReconstructItinerary(requestCtx, &models.ItineraryRequest{
+			Tickets: []models.TicketPair{{"SFO", "JFK"}},
+		})
+		done <- err
+	}()
+
+	// The request gives up while its task is still waiting for a worker
+	cancelRequest()
+	close(release)
+
+	if err := <-done; err != context.Canceled {
+		t.Errorf("ReconstructItinerary() error = %v, want %v", err, context.Canceled)
+	}
+
+	// Once the worker is free the stale task must not run the reconstruction
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), time.Second)
+	defer cancelWait()
+	if _, err := service.ReconstructItinerary(waitCtx, &models.ItineraryRequest{
+		Tickets: []models.TicketPair{{"SFO", "JFK"}},
+	}); err != nil {
+		t.Fatalf("ReconstructItinerary() error = %v", err)
+	}
+	if started.Load() {
+		t.Error("cancelled request still ran its reconstruction")
+	}
+}
+
+func TestReconstructItineraryProcessingTimeout(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+		Reconstruction: config.ReconstructionConfig{
+			ProcessingTimeout: 20 * time.Millisecond,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	// stall returns a context whose reconstruction, once started, holds its worker until
+	// release is closed
+	stall := func() (stalledCtx context.Context, started, release chan struct{}) {
+		started, release = make(chan struct{}), make(chan struct{})
+		stalledCtx = WithProgress(context.Background(), func(_ int, stage Stage, _ int) {
+			if stage == StageTicketsParsed {
+				close(started)
+				<-release
+			}
+		})
+		return stalledCtx, started, release
+	}
+
+	// A batch waits for its tasks, so release the stalled one well past the deadline
+	slowCtx, _, release := stall()
+	time.AfterFunc(5*cfg.Reconstruction.ProcessingTimeout, func() { close(release) })
+	results := service.ReconstructBatch(slowCtx, []models.ItineraryRequest{*chainRequest(10)})
+	if results[0].Err != ErrProcessingTimeout {
+		t.Errorf("ReconstructBatch() error = %v, want %v", results[0].Err, ErrProcessingTimeout)
+	}
+
+	// Requests within the deadline are unaffected
+	if _, err := service.ReconstructItinerary(context.Background(), chainRequest(100)); err != nil {
+		t.Errorf("ReconstructItinerary() error = %v", err)
+	}
+
+	// A single request stops waiting for its stalled task at the deadline
+	slowCtx, _, release = stall()
+	_, err := service.ReconstructItinerary(slowCtx, chainRequest(100))
+	close(release)
+	if err != ErrProcessingTimeout {
+		t.Errorf("ReconstructItinerary() error = %v, want %v", err, ErrProcessingTimeout)
+	}
+
+	// The deadline also covers waiting for a worker, held here by a request on an idle service
+	service = NewItineraryService(ctx, cfg)
+	busyCtx, started, release := stall()
+	defer close(release)
+	go service.ReconstructItinerary(busyCtx, chainRequest(30))
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("ReconstructItinerary() did not start on an idle worker")
+	}
+	if _, err := service.ReconstructItinerary(context.Background(), chainRequest(40)); err != ErrProcessingTimeout {
+		t.Errorf("ReconstructItinerary() waiting for a worker error = %v, want %v", err, ErrProcessingTimeout)
+	}
+}
+
+func TestReconstructItineraryReleasesWorkerFirst(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+		Admission: config.AdmissionConfig{
+			NonBlocking: true,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	// A client sending its requests one after another never finds the worker still held by
+	// its previous request, even though requests that would have to wait are shed
+	for i := 0; i < 200; i++ {
+		if _, err := service.ReconstructItinerary(context.Background(), chainRequest(i%20+1)); err != nil {
+			t.Fatalf("request %d: ReconstructItinerary() error = %v", i, err)
+		}
+	}
+}
+
+func TestReconstructItineraryGoroutines(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 4,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	// Hold all workers so every request stays in flight
+	release := make(chan struct{})
+	blocked := make(chan struct{}, cfg.WorkerPool.WorkerCount)
+	request := &models.ItineraryRequest{Tickets: []models.TicketPair{{"SFO", "JFK"}}}
+	blockingCtx := WithProgress(context.Background(), func(_ int, stage Stage, _ int) {
+		if stage == StageTicketsParsed {
+			blocked <- struct{}{}
+			<-release
+		}
+	})
+
+	baseline := runtime.NumGoroutine()
+
+	done := make(chan struct{})
+	for i := 0; i < cfg.WorkerPool.WorkerCount; i++ {
+		go func() {
+			_, _ = service.ReconstructItinerary(blockingCtx, request)
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < cfg.WorkerPool.WorkerCount; i++ {
+		<-blocked
+	}
+
+	// One goroutine per caller plus one pool worker per request, and no helper goroutines
+	if extra := runtime.NumGoroutine() - baseline; extra > 2*cfg.WorkerPool.WorkerCount {
+		t.Errorf("in-flight requests use %d goroutines, want at most %d", extra, 2*cfg.WorkerPool.WorkerCount)
+	}
+
+	close(release)
+	for i := 0; i < cfg.WorkerPool.WorkerCount; i++ {
+		<-done
+	}
+}
+
+func TestReconstructItineraryRecoversPanics(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+		Stream: config.StreamConfig{
+			MaxInFlight: 1,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	core, logs := observer.New(zap.ErrorLevel)
+	service := NewItineraryService(ctx, cfg, WithLogger(zap.New(core)))
+
+	// A bug anywhere in the reconstruction, simulated by a panicking progress listener
+	panicking := WithProgress(context.Background(), func(_ int, stage Stage, _ int) {
+		if stage == StageGraphBuilt {
+			panic("boom")
+		}
+	})
+
+	itinerary, err := service.ReconstructItinerary(panicking, chainRequest(3))
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || !errors.Is(err, ErrInternal) || itinerary != nil {
+		t.Fatalf("ReconstructItinerary() = %v, %v, want a PanicError", itinerary, err)
+	}
+	if panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Errorf("PanicError = %v with %d bytes of stack, want the panic value and a stack", panicErr.Value, len(panicErr.Stack))
+	}
+
+	requests := make(chan StreamRequest, 1)
+	requests <- StreamRequest{Request: *chainRequest(3)}
+	close(requests)
+	err = service.ReconstructStream(panicking, requests, func(result StreamResult) error {
+		if !errors.Is(result.Err, ErrInternal) {
+			t.Errorf("ReconstructStream() item error = %v, want %v", result.Err, ErrInternal)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("ReconstructStream() error = %v", err)
+	}
+
+	if got := service.Metrics().Counter("itinerary_task_panics_total", "").Value(); got != 2 {
+		t.Errorf("itinerary_task_panics_total = %v, want 2", got)
+	}
+	if entries := logs.FilterMessage("Reconstruction task panicked").Len(); entries != 2 {
+		t.Errorf("logged %d panics, want 2", entries)
+	}
+
+	// The worker survives and keeps serving requests
+	if _, err := service.ReconstructItinerary(context.Background(), chainRequest(3)); err != nil {
+		t.Errorf("ReconstructItinerary() after a panic error = %v", err)
+	}
+}
@@ -0,0 +1,478 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/models"
+)
+
+// JobStatus describes the lifecycle stage of an asynchronous job
+type JobStatus string
+
+// Job lifecycle stages
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Job manager errors
+var (
+	ErrJobQueueFull      = NewError(KindUnavailable, "job_queue_full", "job queue is full")
+	ErrJobNotFound       = NewError(KindNotFound, "job_not_found", "job not found")
+	ErrJobFinished       = NewError(KindConflict, "job_finished", "job already finished")
+	ErrJobsShutdown      = NewError(KindUnavailable, "shutting_down", "job manager is shutting down")
+	ErrNoCallback        = NewError(KindNotFound, "no_callback", "job has no callback URL")
+	ErrCallbacksDisabled = NewError(KindInvalidInput, "callbacks_disabled", "callbacks are not enabled on this server")
+	ErrCallbackForbidden = NewError(KindInvalidInput, "callback_forbidden", "invalid callback URL: must not point to a loopback, private or link-local address")
+)
+
+// jobCleanupDivisor makes cleanup run several times per retention window
+const jobCleanupDivisor = 4
+
+// jobEventBuffer is the number of events buffered per subscriber; slower subscribers miss
+// intermediate events but always observe the end of the job
+const jobEventBuffer = 64
+
+// Job is a point-in-time copy of an asynchronous job's state
+type Job struct {
+	ID         string
+	Status     JobStatus
+	Batch      bool
+	Total      int
+	Completed  int
+	Results    []BatchResult
+	Err        error
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+
+	CallbackURL    string
+	DeliveryStatus DeliveryStatus
+	Deliveries     []DeliveryAttempt
+}
+
+// Finished reports whether the job reached a terminal status
+func (j *Job) Finished() bool {
+	return j.Status == JobCompleted || j.Status == JobFailed || j.Status == JobCancelled
+}
+
+// JobEvent reports the progress of a running job to subscribers
+type JobEvent struct {
+	Item      int
+	Stage     Stage
+	Count     int
+	Completed int
+	Total     int
+}
+
+// jobEntry holds a job's mutable state together with its input, kept until the job has run,
+// its cancellation and subscribers
+type jobEntry struct {
+	job       Job
+	requests  []models.ItineraryRequest
+	ctx       context.Context
+	cancel    context.CancelFunc
+	listeners map[chan JobEvent]struct{}
+}
+
+// JobManager runs reconstruction jobs asynchronously from a bounded queue
+type JobManager struct {
+	service   *ItineraryService
+	notifier  *WebhookNotifier
+	queue     chan *jobEntry
+	jobs      map[string]*jobEntry
+	mu        sync.RWMutex
+	maxItems  int
+	retention time.Duration
+	ctx       context.Context
+}
+
+// NewJobManager creates a new JobManager and starts its workers, which stop when ctx is cancelled
+func NewJobManager(ctx context.Context, cfg *config.AppConfig, service *ItineraryService) *JobManager {
+	m := &JobManager{
+		service:   service,
+		notifier:  NewWebhookNotifier(&cfg.Webhook),
+		queue:     make(chan *jobEntry, cfg.Jobs.QueueSize),
+		jobs:      make(map[string]*jobEntry),
+		maxItems:  cfg.Jobs.MaxItems,
+		retention: cfg.Jobs.ResultRetention,
+		ctx:       ctx,
+	}
+
+	for i := 0; i < cfg.Jobs.Workers; i++ {
+		go m.worker()
+	}
+	if m.retention > 0 {
+		go m.cleanupLoop()
+	}
+
+	return m
+}
+
+// MaxItems returns the maximum number of ticket sets accepted in a single job
+func (m *JobManager) MaxItems() int {
+	return m.maxItems
+}
+
+// Submit enqueues a job for the given request without waiting for it to run
+func (m *JobManager) Submit(request *models.JobRequest) (Job, error) {
+	if m.ctx.Err() != nil {
+		return Job{}, ErrJobsShutdown
+	}
+
+	if request.CallbackURL != "" && !m.notifier.Enabled() {
+		return Job{}, ErrCallbacksDisabled
+	}
+	if request.CallbackURL != "" && !m.notifier.Permits(request.CallbackURL) {
+		return Job{}, ErrCallbackForbidden
+	}
+
+	requests := request.ItineraryRequests()
+	ctx, cancel := context.WithCancel(m.ctx)
+	entry := &jobEntry{
+		job: Job{
+			ID:          newID(),
+			Status:      JobQueued,
+			Batch:       request.IsBatch(),
+			Total:       len(requests),
+			CreatedAt:   time.Now(),
+			CallbackURL: request.CallbackURL,
+		},
+		requests: requests,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case m.queue <- entry:
+	default:
+		cancel()
+		return Job{}, ErrJobQueueFull
+	}
+	m.jobs[entry.job.ID] = entry
+
+	return entry.snapshot(), nil
+}
+
+// Get returns the current state of a job
+func (m *JobManager) Get(id string) (Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entry, exists := m.jobs[id]
+	if !exists {
+		return Job{}, ErrJobNotFound
+	}
+	return entry.snapshot(), nil
+}
+
+// Cancel stops a queued or running job
+func (m *JobManager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, exists := m.jobs[id]
+	if !exists {
+		return Job{}, ErrJobNotFound
+	}
+	if entry.job.Finished() {
+		return entry.snapshot(), ErrJobFinished
+	}
+
+	entry.cancel()
+	m.finish(entry, JobCancelled, context.Canceled)
+	return entry.snapshot(), nil
+}
+
+// Subscribe returns the current state of a job together with a channel of its progress
+// events. The channel is closed once the job finishes; unsubscribe releases it earlier.
+func (m *JobManager) Subscribe(id string) (Job, <-chan JobEvent, func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, exists := m.jobs[id]
+	if !exists {
+		return Job{}, nil, nil, ErrJobNotFound
+	}
+
+	events := make(chan JobEvent, jobEventBuffer)
+	if entry.job.Finished() {
+		close(events)
+		return entry.snapshot(), events, func() {}, nil
+	}
+
+	if entry.listeners == nil {
+		entry.listeners = make(map[chan JobEvent]struct{})
+	}
+	entry.listeners[events] = struct{}{}
+
+	unsubscribe := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, subscribed := entry.listeners[events]; subscribed {
+			delete(entry.listeners, events)
+			close(events)
+		}
+	}
+
+	return entry.snapshot(), events, unsubscribe, nil
+}
+
+// Deliveries returns the state of a job including its callback delivery attempts
+func (m *JobManager) Deliveries(id string) (Job, error) {
+	job, err := m.Get(id)
+	if err != nil {
+		return Job{}, err
+	}
+	if job.CallbackURL == "" {
+		return Job{}, ErrNoCallback
+	}
+	return job, nil
+}
+
+// worker runs queued jobs one at a time until the manager is shut down
+func (m *JobManager) worker() {
+	for {
+		select {
+		case entry := <-m.queue:
+			m.run(entry)
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+// run processes every ticket set of a job on the service, recording progress as results arrive
+func (m *JobManager) run(entry *jobEntry) {
+	m.mu.Lock()
+	if entry.job.Finished() {
+		// Cancelled while still queued
+		entry.requests = nil
+		m.mu.Unlock()
+		return
+	}
+	entry.job.Status = JobRunning
+	entry.job.StartedAt = time.Now()
+	entry.job.Results = make([]BatchResult, len(entry.requests))
+	m.mu.Unlock()
+
+	requests := make(chan StreamRequest)
+	fed := make(chan struct{})
+	go func() {
+		defer close(fed)
+		defer close(requests)
+		for i, request := range entry.requests {
+			select {
+			case requests <- StreamRequest{Index: i, Request: request}:
+			case <-entry.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Forward reconstruction stages of every ticket set to subscribers
+	// Jobs are background work and never delay interactive requests
+	ctx := WithProgress(WithPriority(entry.ctx, PriorityBulk), func(index int, stage Stage, count int) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		entry.publish(JobEvent{Item: index, Stage: stage, Count: count})
+	})
+
+	err := m.service.ReconstructStream(ctx, requests, func(result StreamResult) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		entry.job.Results[result.Index] = BatchResult{Itinerary: result.Itinerary, Err: result.Err}
+		entry.job.Completed++
+		entry.publish(JobEvent{Item: result.Index, Stage: StageCompleted, Count: len(result.Itinerary)})
+		return nil
+	})
+	entry.cancel()
+	<-fed
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Only the results are retained once the job has run
+	entry.requests = nil
+
+	if entry.job.Finished() {
+		return
+	}
+
+	switch {
+	case err != nil:
+		m.finish(entry, JobCancelled, err)
+	case !entry.job.Batch && entry.job.Results[0].Err != nil:
+		// A single ticket set job fails with its only error
+		m.finish(entry, JobFailed, entry.job.Results[0].Err)
+	default:
+		m.finish(entry, JobCompleted, nil)
+	}
+}
+
+// finish moves the job to a terminal status and schedules its callback;
+// callers must hold the manager lock
+func (m *JobManager) finish(entry *jobEntry, status JobStatus, err error) {
+	entry.job.Status = status
+	entry.job.Err = err
+	entry.job.FinishedAt = time.Now()
+
+	// Closing the event channels tells subscribers the job is over
+	for events := range entry.listeners {
+		close(events)
+	}
+	entry.listeners = nil
+
+	if entry.job.CallbackURL != "" {
+		entry.job.DeliveryStatus = DeliveryPending
+		go m.deliver(entry, entry.snapshot())
+	}
+}
+
+// deliver posts the final job state to its callback URL, recording every attempt
+func (m *JobManager) deliver(entry *jobEntry, job Job) {
+	response := job.Response()
+	payload, err := json.Marshal(response)
+	if err != nil {
+		m.mu.Lock()
+		entry.job.DeliveryStatus = DeliveryFailed
+		m.mu.Unlock()
+		return
+	}
+
+	status := m.notifier.Deliver(m.ctx, job.CallbackURL, job.ID, payload, func(attempt DeliveryAttempt) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		entry.job.Deliveries = append(entry.job.Deliveries, attempt)
+	})
+
+	m.mu.Lock()
+	entry.job.DeliveryStatus = status
+	m.mu.Unlock()
+}
+
+// cleanupLoop periodically removes finished jobs older than the retention window
+func (m *JobManager) cleanupLoop() {
+	ticker := time.NewTicker(m.retention / time.Duration(jobCleanupDivisor))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.cleanup()
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+// cleanup removes finished jobs whose retention window has elapsed
+func (m *JobManager) cleanup() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	threshold := time.Now().Add(-m.retention)
+	for id, entry := range m.jobs {
+		if entry.job.Finished() && entry.job.FinishedAt.Before(threshold) {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// publish sends an event to every subscriber without blocking, dropping it for subscribers
+// whose buffer is full. Callers must hold the manager lock.
+func (e *jobEntry) publish(event JobEvent) {
+	event.Completed = e.job.Completed
+	event.Total = e.job.Total
+
+	for events := range e.listeners {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// snapshot copies the job state; results are only exposed once the job has finished.
+// Callers must hold the manager lock.
+func (e *jobEntry) snapshot() Job {
+	job := e.job
+	if !job.Finished() || job.Status == JobCancelled {
+		job.Results = nil
+	} else {
+		job.Results = append([]BatchResult(nil), job.Results...)
+	}
+	job.Deliveries = append([]DeliveryAttempt(nil), job.Deliveries...)
+	return job
+}
+
+// Response converts a job snapshot into its API representation
+func (j *Job) Response() models.JobResponse {
+	return j.ResponseWith(error.Error)
+}
+
+// ResponseWith converts a job snapshot into its API representation, rendering the messages
+// of failures with message
+func (j *Job) ResponseWith(message func(error) string) models.JobResponse {
+	response := models.JobResponse{
+		ID:     j.ID,
+		Status: string(j.Status),
+		Progress: models.JobProgress{
+			Total:     j.Total,
+			Completed: j.Completed,
+		},
+		CallbackURL: j.CallbackURL,
+		CreatedAt:   j.CreatedAt,
+	}
+
+	if !j.StartedAt.IsZero() {
+		response.StartedAt = &j.StartedAt
+	}
+	if !j.FinishedAt.IsZero() {
+		response.FinishedAt = &j.FinishedAt
+	}
+	if j.Err != nil {
+		response.Error = message(j.Err)
+		response.Code = ErrorCode(j.Err)
+	}
+
+	if j.Status != JobCompleted {
+		return response
+	}
+
+	if !j.Batch {
+		response.Itinerary = j.Results[0].Itinerary
+		return response
+	}
+
+	response.Results = make([]models.BatchItemResult, len(j.Results))
+	for i, result := range j.Results {
+		if result.Err != nil {
+			response.Results[i].Error = message(result.Err)
+			response.Results[i].Code = ErrorCode(result.Err)
+			continue
+		}
+		response.Results[i].Itinerary = result.Itinerary
+	}
+	return response
+}
+
+// newID returns a random, URL-safe identifier for jobs and stored itineraries
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/models"
+)
+
+// newTestJobManager creates a job manager backed by a small service
+func newTestJobManager(t *testing.T, jobs config.JobsConfig) *JobManager {
+	t.Helper()
+
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 2,
+		},
+		Jobs: jobs,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return NewJobManager(ctx, cfg, NewItineraryService(ctx, cfg))
+}
+
+// waitForJob polls a job until it reaches a terminal status
+func waitForJob(t *testing.T, m *JobManager, id string) Job {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if job.Finished() {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish in time", id)
+	return Job{}
+}
+
+func TestJobManagerRunsJobs(t *testing.T) {
+	m := newTestJobManager(t, config.JobsConfig{QueueSize: 4, Workers: 1, MaxItems: 10})
+
+	tests := []struct {
+		name        string
+		request     *models.JobRequest
+		wantStatus  JobStatus
+		wantResults []BatchResult
+	}{
+		{
+			name:        "single ticket set",
+			request:     &models.JobRequest{Tickets: []models.TicketPair{{"LAX", "JFK"}, {"SFO", "LAX"}}},
+			wantStatus:  JobCompleted,
+			wantResults: []BatchResult{{Itinerary: []string{"SFO", "LAX", "JFK"}}},
+		},
+		{
+			name:       "single invalid ticket set",
+			request:    &models.JobRequest{Tickets: []models.TicketPair{{"SFO", "LAX"}, {"JFK", "MCO"}}},
+			wantStatus: JobFailed,
+		},
+		{
+			name: "batch with a failing item",
+			request: &models.JobRequest{Requests: []models.ItineraryRequest{
+				{Tickets: []models.TicketPair{{"SFO", "JFK"}}},
+				{Tickets: []models.TicketPair{}},
+			}},
+			wantStatus: JobCompleted,
+			wantResults: []BatchResult{
+				{Itinerary: []string{"SFO", "JFK"}},
+				{Err: &models.ValidationError{Code: "no_tickets", Message: "no tickets provided"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			submitted, err := m.Submit(tt.request)
+			if err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+
+			job := waitForJob(t, m, submitted.ID)
+			if job.Status != tt.wantStatus {
+				t.Fatalf("job status = %v, want %v (err: %v)", job.Status, tt.wantStatus, job.Err)
+			}
+			if job.Completed != job.Total {
+				t.Errorf("job progress = %d/%d, want complete", job.Completed, job.Total)
+			}
+			if tt.wantResults != nil && !reflect.DeepEqual(job.Results, tt.wantResults) {
+				t.Errorf("job results = %+v, want %+v", job.Results, tt.wantResults)
+			}
+
+			// Only the results are retained once the job has run
+			m.mu.RLock()
+			requests := m.jobs[job.ID].requests
+			m.mu.RUnlock()
+			if requests != nil {
+				t.Errorf("finished job retains its %d ticket sets", len(requests))
+			}
+		})
+	}
+}
+
+func TestJobManagerQueueAndCancel(t *testing.T) {
+	// Without workers, jobs stay queued until cancelled
+	m := newTestJobManager(t, config.JobsConfig{QueueSize: 1, MaxItems: 10})
+	request := &models.JobRequest{Tickets: []models.TicketPair{{"SFO", "JFK"}}}
+
+	job, err := m.Submit(request)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if job.Status != JobQueued {
+		t.Errorf("job status = %v, want %v", job.Status, JobQueued)
+	}
+
+	if _, err := m.Submit(request); !errors.Is(err, ErrJobQueueFull) {
+		t.Errorf("Submit() on a full queue error = %v, want %v", err, ErrJobQueueFull)
+	}
+
+	cancelled, err := m.Cancel(job.ID)
+	if err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if cancelled.Status != JobCancelled {
+		t.Errorf("job status = %v, want %v", cancelled.Status, JobCancelled)
+	}
+
+	if _, err := m.Cancel(job.ID); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("second Cancel() error = %v, want %v", err, ErrJobFinished)
+	}
+	if _, err := m.Get("unknown"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Get() error = %v, want %v", err, ErrJobNotFound)
+	}
+}
+
+func TestJobManagerCleanup(t *testing.T) {
+	m := newTestJobManager(t, config.JobsConfig{QueueSize: 1, Workers: 1, MaxItems: 10, ResultRetention: time.Hour})
+
+	job, err := m.Submit(&models.JobRequest{Tickets: []models.TicketPair{{"SFO", "JFK"}}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	waitForJob(t, m, job.ID)
+
+	// Finished jobs are kept within the retention window
+	m.cleanup()
+	if _, err := m.Get(job.ID); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	// And removed once it has elapsed
+	m.mu.Lock()
+	m.jobs[job.ID].job.FinishedAt = time.Now().Add(-2 * time.Hour)
+	m.mu.Unlock()
+	m.cleanup()
+	if _, err := m.Get(job.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Get() after retention error = %v, want %v", err, ErrJobNotFound)
+	}
+}
+
+func TestJobManagerSubscribe(t *testing.T) {
+	// Jobs stay queued until a worker is started by hand
+	m := newTestJobManager(t, config.JobsConfig{QueueSize: 1, MaxItems: 10})
+
+	job, err := m.Submit(&models.JobRequest{Requests: []models.ItineraryRequest{
+		{Tickets: []models.TicketPair{{"LAX", "JFK"}, {"SFO", "LAX"}}},
+	}})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	current, events, unsubscribe, err := m.Subscribe(job.ID)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer unsubscribe()
+	if current.Status != JobQueued {
+		t.Errorf("subscribed job status = %v, want %v", current.Status, JobQueued)
+	}
+
+	go m.worker()
+
+	var stages []Stage
+	for event := range events {
+		stages = append(stages, event.Stage)
+	}
+
+	wantStages := []Stage{StageTicketsParsed, StageGraphBuilt, StageComponentsFound, StageCompleted}
+	if !reflect.DeepEqual(stages, wantStages) {
+		t.Errorf("event stages = %v, want %v", stages, wantStages)
+	}
+
+	// Subscribing to a finished job yields a closed channel
+	finished, events, _, err := m.Subscribe(job.ID)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if _, open := <-events; open || finished.Status != JobCompleted {
+		t.Errorf("Subscribe() on finished job: status = %v, channel open = %v", finished.Status, open)
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"math/bits"
+	"sync"
+)
+
+// walkParallel reconstructs the itinerary from start like walk, but ranks the route with
+// pointer jumping across workers goroutines instead of following it one flight at a time.
+//
+// Every airport first points at its successor with rank 1 (0 for the final destination).
+// Each round, all airports add their successor's rank to their own and skip to their
+// successor's successor, so after O(log n) rounds an airport's rank is its distance to the
+// final destination and its position in the itinerary follows directly.
+//
+// The context is checked between rounds.
+//
+// Ranking only yields positions when the route is a simple path through every airport.
+// Anything else (invalid input, or a valid route that revisits an airport) is handed to the
+// serial walk, which produces the exact same result or error as for small inputs.
+func (g *routeGraph) walkParallel(ctx context.Context, start int32, workers int) ([]string, error) {
+	n := len(g.names)
+	if n != g.tickets+1 || workers < 2 {
+		return g.walk(ctx, start)
+	}
+
+	rank := make([]int32, n)
+	succ := make([]int32, n)
+	nextRank := make([]int32, n)
+	nextSucc := make([]int32, n)
+
+	parallelFor(n, workers, func(lo, hi int) bool {
+		for i := lo; i < hi; i++ {
+			succ[i] = g.next[i]
+			if succ[i] != noNode {
+				rank[i] = 1
+			}
+		}
+		return false
+	})
+
+	// A path through n airports converges within log2(n) rounds; a cycle never does
+	maxRounds := bits.Len(uint(n)) + 1
+	for round := 0; round < maxRounds; round++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		pending := parallelFor(n, workers, func(lo, hi int) bool {
+			pending := false
+			for i := lo; i < hi; i++ {
+				s := succ[i]
+				if s == noNode {
+					nextRank[i], nextSucc[i] = rank[i], noNode
+					continue
+				}
+				nextRank[i] = rank[i] + rank[s]
+				nextSucc[i] = succ[s]
+				pending = pending || nextSucc[i] != noNode
+			}
+			return pending
+		})
+
+		rank, nextRank = nextRank, rank
+		succ, nextSucc = nextSucc, succ
+		if !pending {
+			break
+		}
+	}
+
+	if int(rank[start]) != g.tickets {
+		return g.walk(ctx, start)
+	}
+
+	// Every airport sits exactly rank hops before the final destination
+	itinerary := make([]string, n)
+	parallelFor(n, workers, func(lo, hi int) bool {
+		for i := lo; i < hi; i++ {
+			itinerary[g.tickets-int(rank[i])] = g.names[i]
+		}
+		return false
+	})
+
+	return itinerary, nil
+}
+
+// parallelFor splits [0, n) into one contiguous chunk per worker, runs fn on every chunk
+// concurrently and reports whether any chunk returned true. A panic in any chunk is re-raised
+// in the calling goroutine once every chunk has stopped, where the task can recover it.
+func parallelFor(n, workers int, fn func(lo, hi int) bool) bool {
+	chunk := (n + workers - 1) / workers
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		result    bool
+		panicked  bool
+		recovered interface{}
+	)
+	for lo := 0; lo < n; lo += chunk {
+		hi := min(lo+chunk, n)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if value := recover(); value != nil {
+					mu.Lock()
+					panicked, recovered = true, value
+					mu.Unlock()
+				}
+			}()
+
+			if fn(lo, hi) {
+				mu.Lock()
+				result = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if panicked {
+		panic(recovered)
+	}
+	return result
+}
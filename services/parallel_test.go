@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/models"
+)
+
+// maxChainTickets is the length of the longest route visiting every 3-letter uppercase code once
+const maxChainTickets = 26*26*26 - 1
+
+// shuffledChainRequest builds a linear route of n tickets between 3-letter airport codes, in
+// shuffled order; n is at most maxChainTickets
+func shuffledChainRequest(n int) *models.ItineraryRequest {
+	request := chainRequest(n)
+	rand.New(rand.NewSource(1)).Shuffle(n, func(i, j int) {
+		request.Tickets[i], request.Tickets[j] = request.Tickets[j], request.Tickets[i]
+	})
+	return request
+}
+
+// newParallelTestService returns a service walking routes of at least threshold tickets in
+// parallel, or always serially for a zero threshold
+func newParallelTestService(ctx context.Context, threshold int) *ItineraryService {
+	return NewItineraryService(ctx, &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+		Reconstruction: config.ReconstructionConfig{
+			ParallelThreshold: threshold,
+			ParallelWorkers:   4,
+		},
+	})
+}
+
+func TestReconstructItineraryParallel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serial := newParallelTestService(ctx, 0)
+	parallel := newParallelTestService(ctx, 1)
+
+	tests := []struct {
+		name    string
+		request *models.ItineraryRequest
+		wantErr bool
+	}{
+		{
+			name:    "longest shuffled route",
+			request: shuffledChainRequest(maxChainTickets),
+		},
+		{
+			name:    "reversed route",
+			request: chainRequest(5000),
+		},
+		{
+			name: "route ending in a loop",
+			request: &models.ItineraryRequest{Tickets: []models.TicketPair{
+				{"LAX", "DFW"}, {"SFO", "LAX"}, {"DFW", "LAX"},
+			}},
+		},
+		{
+			name: "route revisiting an airport",
+			request: &models.ItineraryRequest{Tickets: []models.TicketPair{
+				{"JFK", "SFO"}, {"SFO", "LAX"}, {"LAX", "JFK"}, {"BOS", "JFK"},
+			}},
+		},
+		{
+			name: "disconnected route with a cycle",
+			request: &models.ItineraryRequest{Tickets: []models.TicketPair{
+				{"SFO", "LAX"}, {"ATL", "MCO"}, {"MCO", "ATL"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "complex with missing segment",
+			request: &models.ItineraryRequest{Tickets: []models.TicketPair{
+				{"SFO", "LAX"}, {"LAX", "DFW"}, {"ORD", "JFK"},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Only requests the API would accept are reconstructed
+			if err := tt.request.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			want, wantErr := serial.ReconstructItinerary(ctx, tt.request)
+			got, err := parallel.ReconstructItinerary(ctx, tt.request)
+
+			if (err != nil) != tt.wantErr || err != wantErr {
+				t.Fatalf("parallel error = %v, serial error = %v, wantErr %v", err, wantErr, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("parallel itinerary differs from serial (lengths %d and %d)", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestParallelFor(t *testing.T) {
+	for _, n := range []int{1, 7, 100} {
+		covered := make([]int, n)
+		reported := parallelFor(n, 3, func(lo, hi int) bool {
+			for i := lo; i < hi; i++ {
+				covered[i]++
+			}
+			return lo == 0
+		})
+
+		if !reported {
+			t.Errorf("parallelFor(%d) did not report the first chunk's result", n)
+		}
+		for i, count := range covered {
+			if count != 1 {
+				t.Fatalf("parallelFor(%d) visited index %d %d times", n, i, count)
+			}
+		}
+	}
+}
+
+// asciiChainRequest builds a shuffled linear route of n tickets between 3-character codes
+// of printable ASCII, which validation accepts, for routes longer than 3-letter codes allow
+func asciiChainRequest(n int) *models.ItineraryRequest {
+	const first, symbols = '!', '~' - '!' + 1
+	code := func(i int) string {
+		return string([]byte{byte(first + i/(symbols*symbols)%symbols), byte(first + i/symbols%symbols), byte(first + i%symbols)})
+	}
+
+	tickets := make([]models.TicketPair, n)
+	for i := 0; i < n; i++ {
+		tickets[i] = models.TicketPair{code(i), code(i + 1)}
+	}
+	rand.New(rand.NewSource(1)).Shuffle(n, func(i, j int) {
+		tickets[i], tickets[j] = tickets[j], tickets[i]
+	})
+	return &models.ItineraryRequest{Tickets: tickets}
+}
+
+// BenchmarkWalk compares the serial and parallel walks of routes around the parallel
+// threshold, on graphs built beforehand, with one parallel worker per GOMAXPROCS. Run it with
+// -cpu set to the cores of the target machine to choose PARALLEL_THRESHOLD.
+func BenchmarkWalk(b *testing.B) {
+	for _, n := range []int{10000, maxChainTickets, 100000, 500000} {
+		request := asciiChainRequest(n)
+		if err := request.Validate(); err != nil {
+			b.Fatalf("Validate() error = %v", err)
+		}
+
+		graph := acquireRouteGraph()
+		for _, ticket := range request.Tickets {
+			if err := graph.addTicket(ticket[0], ticket[1]); err != nil {
+				b.Fatal(err)
+			}
+		}
+		start, _, err := graph.findStarts(context.Background())
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for _, bm := range []struct {
+			name string
+			walk func() ([]string, error)
+		}{
+			{name: "serial", walk: func() ([]string, error) {
+				return graph.walk(context.Background(), start)
+			}},
+			{name: "parallel", walk: func() ([]string, error) {
+				return graph.walkParallel(context.Background(), start, runtime.GOMAXPROCS(0))
+			}},
+		} {
+			b.Run(fmt.Sprintf("tickets=%d/%s", n, bm.name), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, err := bm.walk(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+		graph.release()
+	}
+}
+
+func TestParallelForPropagatesPanics(t *testing.T) {
+	defer func() {
+		if value := recover(); value != "chunk failed" {
+			t.Errorf("recovered %v, want the chunk's panic", value)
+		}
+	}()
+
+	parallelFor(100, 4, func(lo, hi int) bool {
+		if lo == 0 {
+			panic("chunk failed")
+		}
+		return false
+	})
+	t.Error("parallelFor() returned despite a panicking chunk")
+}
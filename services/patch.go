@@ -0,0 +1,105 @@
+package services
+
+import (
+	"flight-itinerary-api/models"
+)
+
+// Patch errors
+var (
+	ErrTicketNotFound = NewError(KindConflict, "ticket_not_found", "ticket to remove or replace is not part of the itinerary")
+	ErrNoTicketsLeft  = NewError(KindInvalidInput, "no_tickets_left", "the patch would remove every ticket of the itinerary")
+)
+
+// segment is a run of consecutive airports of a route
+type segment []string
+
+// spliceItinerary returns the itinerary of the route left after removing tickets from, then
+// adding tickets to, a valid itinerary. Rather than rebuilding the flight graph, it cuts the
+// route at the removed flights and chains the resulting segments with the added tickets,
+// which costs time proportional to the size of the change plus copying the result. Invalid
+// routes fail with the same errors as a full reconstruction.
+func spliceItinerary(itinerary []string, remove, add []models.TicketPair) ([]string, error) {
+	// Flights of a valid itinerary have distinct sources, so a source locates its flight
+	legs := len(itinerary) - 1
+	legFrom := make(map[string]int, legs)
+	for i := 0; i < legs; i++ {
+		legFrom[itinerary[i]] = i
+	}
+
+	removed := make(map[int]bool, len(remove))
+	for _, ticket := range remove {
+		i, found := legFrom[ticket[0]]
+		if !found || itinerary[i+1] != ticket[1] || removed[i] {
+			return nil, ErrTicketNotFound
+		}
+		removed[i] = true
+	}
+	if len(removed) == legs && len(add) == 0 {
+		return nil, ErrNoTicketsLeft
+	}
+
+	// Cut the route at the removed flights, dropping the airports left without flights
+	segments := make([]segment, 0, len(removed)+len(add)+1)
+	lo := 0
+	for i := 0; i <= legs; i++ {
+		if i == legs || removed[i] {
+			if i > lo {
+				segments = append(segments, itinerary[lo:i+1])
+			}
+			lo = i + 1
+		}
+	}
+
+	for _, ticket := range add {
+		if i, found := legFrom[ticket[0]]; found && !removed[i] {
+			return nil, errDuplicateSource
+		}
+		segments = append(segments, segment{ticket[0], ticket[1]})
+	}
+
+	return chainSegments(segments, legs-len(removed)+len(add))
+}
+
+// chainSegments joins segments end to start into a single route of the given number of
+// flights, failing unless exactly one segment starts the route and the route uses them all
+func chainSegments(segments []segment, flights int) ([]string, error) {
+	byStart := make(map[string]int, len(segments))
+	ends := make(map[string]bool, len(segments))
+	for i, s := range segments {
+		if _, found := byStart[s[0]]; found {
+			return nil, errDuplicateSource
+		}
+		byStart[s[0]] = i
+		ends[s[len(s)-1]] = true
+	}
+
+	// Only segment starts may lack an incoming flight; every other airport follows one
+	start, starts := -1, 0
+	for i, s := range segments {
+		if !ends[s[0]] {
+			start = i
+			starts++
+		}
+	}
+	if starts > 1 {
+		return nil, errMultipleStarts
+	}
+	if starts == 0 {
+		return nil, errNoStart
+	}
+
+	// A route looping back onto itself would revisit segments instead of using them all
+	used := make([]bool, len(segments))
+	used[start] = true
+	route := make([]string, 0, flights+1)
+	route = append(route, segments[start]...)
+	for visited := 1; visited < len(segments); visited++ {
+		next, found := byStart[route[len(route)-1]]
+		if !found || used[next] {
+			return nil, errDisconnectedRoute
+		}
+		used[next] = true
+		route = append(route, segments[next][1:]...)
+	}
+	return route, nil
+}
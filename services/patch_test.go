@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/models"
+)
+
+func TestSpliceItinerary(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	route := []models.TicketPair{{"SFO", "LAX"}, {"LAX", "JFK"}, {"JFK", "MCO"}}
+
+	tests := []struct {
+		name    string
+		tickets []models.TicketPair
+		remove  []models.TicketPair
+		add     []models.TicketPair
+	}{
+		{"add at the end", route, nil, []models.TicketPair{{"MCO", "ATL"}}},
+		{"add at the start", route, nil, []models.TicketPair{{"SEA", "SFO"}}},
+		{"remove the first flight", route, []models.TicketPair{{"SFO", "LAX"}}, nil},
+		{"remove the last flight", route, []models.TicketPair{{"JFK", "MCO"}}, nil},
+		{"reroute through another airport", route, []models.TicketPair{{"LAX", "JFK"}}, []models.TicketPair{{"LAX", "DEN"}, {"DEN", "JFK"}}},
+		{"replace every flight", route, route, []models.TicketPair{{"BOS", "ORD"}}},
+		{"revisit an airport", route, nil, []models.TicketPair{{"MCO", "LAX"}}},
+		{"split the route", route, []models.TicketPair{{"LAX", "JFK"}}, nil},
+		{"duplicate source", route, nil, []models.TicketPair{{"LAX", "DEN"}}},
+		{"duplicate added sources", route, nil, []models.TicketPair{{"MCO", "ATL"}, {"MCO", "DEN"}}},
+		{"close a loop", route, []models.TicketPair{{"SFO", "LAX"}}, []models.TicketPair{{"MCO", "LAX"}}},
+		{"detached loop", route, nil, []models.TicketPair{{"ATL", "DEN"}, {"DEN", "ATL"}}},
+		{"loop back after the end", []models.TicketPair{{"SFO", "LAX"}, {"LAX", "JFK"}}, nil, []models.TicketPair{{"JFK", "LAX"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			itinerary, err := service.ReconstructItinerary(ctx, &models.ItineraryRequest{Tickets: tt.tickets})
+			if err != nil {
+				t.Fatalf("ReconstructItinerary() error = %v", err)
+			}
+
+			// The spliced route matches a full reconstruction of the patched tickets
+			patch := &models.ItineraryPatch{Remove: tt.remove, Add: tt.add}
+			want, wantErr := service.ReconstructItinerary(ctx, &models.ItineraryRequest{Tickets: patchTickets(tt.tickets, patch)})
+
+			got, err := spliceItinerary(itinerary, tt.remove, tt.add)
+			if ErrorCode(err) != ErrorCode(wantErr) && !(err == nil && wantErr == nil) {
+				t.Fatalf("spliceItinerary() error = %v, want %v", err, wantErr)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("spliceItinerary() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSpliceItineraryErrors(t *testing.T) {
+	itinerary := []string{"SFO", "LAX", "JFK"}
+
+	tests := []struct {
+		name    string
+		remove  []models.TicketPair
+		add     []models.TicketPair
+		wantErr error
+	}{
+		{"unknown ticket", []models.TicketPair{{"SFO", "JFK"}}, nil, ErrTicketNotFound},
+		{"ticket removed twice", []models.TicketPair{{"SFO", "LAX"}, {"SFO", "LAX"}}, nil, ErrTicketNotFound},
+		{"every ticket removed", []models.TicketPair{{"SFO", "LAX"}, {"LAX", "JFK"}}, nil, ErrNoTicketsLeft},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := spliceItinerary(itinerary, tt.remove, tt.add); err != tt.wantErr {
+				t.Errorf("spliceItinerary() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
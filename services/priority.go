@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+
+	"flight-itinerary-api/config"
+)
+
+// Priority is the scheduling class of a reconstruction. Lower values are served first.
+type Priority int
+
+// Priority classes, from most to least urgent
+const (
+	PriorityInteractive Priority = iota
+	PriorityBulk
+
+	numPriorities
+)
+
+// String returns the configuration name of the priority
+func (p Priority) String() string {
+	switch p {
+	case PriorityBulk:
+		return config.PriorityBulk
+	default:
+		return config.PriorityInteractive
+	}
+}
+
+// ParsePriority returns the priority with the given configuration name
+func ParsePriority(name string) (Priority, bool) {
+	switch name {
+	case config.PriorityInteractive:
+		return PriorityInteractive, true
+	case config.PriorityBulk:
+		return PriorityBulk, true
+	default:
+		return 0, false
+	}
+}
+
+// priorityKey is the context key under which a Priority is stored
+type priorityKey struct{}
+
+// WithPriority returns a context whose reconstructions are scheduled with priority p
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority carried by ctx, defaulting to PriorityInteractive
+func PriorityFromContext(ctx context.Context) Priority {
+	p, ok := ctx.Value(priorityKey{}).(Priority)
+	if !ok || p < 0 || p >= numPriorities {
+		return PriorityInteractive
+	}
+	return p
+}
@@ -0,0 +1,39 @@
+package services
+
+import "context"
+
+// Stage identifies a step of itinerary reconstruction reported to progress listeners
+type Stage string
+
+// Reconstruction stages, in the order they are reported for a ticket set
+const (
+	StageTicketsParsed   Stage = "tickets_parsed"
+	StageGraphBuilt      Stage = "graph_built"
+	StageComponentsFound Stage = "components_found"
+	StageCompleted       Stage = "completed"
+)
+
+// ProgressFunc receives reconstruction progress for the ticket set at index. Count is the
+// number of tickets parsed, airports in the graph, or starting points found, depending on
+// the stage. It is called from worker goroutines and must not block.
+type ProgressFunc func(index int, stage Stage, count int)
+
+// progressKey is the context key under which a ProgressFunc is stored
+type progressKey struct{}
+
+// WithProgress returns a context that makes the service report reconstruction progress to fn
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressReporter returns the reporting function for the ticket set at index, which is a
+// no-op when ctx carries no ProgressFunc
+func progressReporter(ctx context.Context, index int) func(Stage, int) {
+	fn, ok := ctx.Value(progressKey{}).(ProgressFunc)
+	if !ok || fn == nil {
+		return func(Stage, int) {}
+	}
+	return func(stage Stage, count int) {
+		fn(index, stage, count)
+	}
+}
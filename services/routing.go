@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/metrics"
+)
+
+// Worker pool names, as reported in metrics
+const (
+	poolSmall = "small"
+	poolLarge = "large"
+)
+
+// workerPool is an executor together with the admission control in front of it
+type workerPool struct {
+	executor  Executor
+	admission *admission
+}
+
+// newWorkerPool creates a pool of workers workers, whose executor is released when ctx is done
+func newWorkerPool(ctx context.Context, name string, cfg *config.WorkerPoolConfig, admissionCfg *config.AdmissionConfig, registry *metrics.Registry) *workerPool {
+	executor := NewExecutor(cfg)
+
+	// Start a goroutine to watch for context cancellation
+	go func() {
+		<-ctx.Done()
+		executor.Release()
+	}()
+
+	return &workerPool{
+		executor:  executor,
+		admission: newAdmission(cfg.WorkerCount, admissionCfg, registry, name),
+	}
+}
+
+// newLargePool creates the pool dedicated to large requests, or returns nil when it is disabled.
+// It shares the executor kind, client weights and retry delay of the main pool but has its own
+// size and queue limits, and is not shed on latency since large requests are slow by nature.
+func newLargePool(ctx context.Context, cfg *config.AppConfig, registry *metrics.Registry) *workerPool {
+	if cfg.LargePool.Threshold <= 0 || cfg.LargePool.WorkerCount <= 0 {
+		return nil
+	}
+
+	poolCfg := config.WorkerPoolConfig{
+		WorkerCount: cfg.LargePool.WorkerCount,
+		Executor:    cfg.WorkerPool.Executor,
+	}
+	admissionCfg := cfg.Admission
+	admissionCfg.MaxQueueDepth = cfg.LargePool.MaxQueueDepth
+	admissionCfg.MaxQueueWait = cfg.LargePool.MaxQueueWait
+	admissionCfg.TargetLatency = 0
+
+	return newWorkerPool(ctx, poolLarge, &poolCfg, &admissionCfg, registry)
+}
+
+// poolFor returns the pool running a request of the given cost, measured in tickets
+func (s *ItineraryService) poolFor(cost int) *workerPool {
+	if s.large != nil && cost >= s.largeThreshold {
+		return s.large
+	}
+	return s.small
+}
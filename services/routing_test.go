@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"flight-itinerary-api/config"
+)
+
+func TestReconstructItineraryRoutesByCost(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 1,
+		},
+		LargePool: config.LargePoolConfig{
+			Threshold:   100,
+			WorkerCount: 1,
+		},
+		Admission: config.AdmissionConfig{
+			NonBlocking: true,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	// Stall a large request on the only large worker
+	started := make(chan struct{})
+	release := make(chan struct{})
+	stalledCtx := WithProgress(context.Background(), func(_ int, stage Stage, _ int) {
+		if stage == StageTicketsParsed {
+			close(started)
+			<-release
+		}
+	})
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.ReconstructItinerary(stalledCtx, chainRequest(100))
+		done <- err
+	}()
+	<-started
+
+	// Small requests keep flowing through the main pool
+	for i := 0; i < 3; i++ {
+		if _, err := service.ReconstructItinerary(context.Background(), chainRequest(99)); err != nil {
+			t.Fatalf("small ReconstructItinerary() error = %v", err)
+		}
+	}
+
+	// Another large request is limited by the large pool alone
+	if _, err := service.ReconstructItinerary(context.Background(), chainRequest(100)); err != ErrOverloaded {
+		t.Errorf("large ReconstructItinerary() error = %v, want %v", err, ErrOverloaded)
+	}
+
+	close(release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("stalled ReconstructItinerary() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stalled request did not finish")
+	}
+
+	counter := func(pool string) float64 {
+		return service.Metrics().Counter("itinerary_admitted_total", "", "pool", pool, "priority", PriorityInteractive.String()).Value()
+	}
+	if small, large := counter(poolSmall), counter(poolLarge); small != 3 || large != 1 {
+		t.Errorf("admitted small = %v, large = %v, want 3 and 1", small, large)
+	}
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"flight-itinerary-api/models"
+	"flight-itinerary-api/repository"
+)
+
+// Itinerary store errors
+var (
+	ErrItineraryNotFound  = NewError(KindNotFound, "itinerary_not_found", "itinerary not found")
+	ErrRevisionNotFound   = NewError(KindNotFound, "revision_not_found", "itinerary revision not found")
+	ErrInvalidCursor      = NewError(KindInvalidInput, "invalid_cursor", "invalid cursor: pass the next_cursor of a previous page")
+	ErrPreconditionFailed = NewError(KindPreconditionFailed, "precondition_failed", "the itinerary was modified: its revision does not match If-Match")
+)
+
+// ItineraryStore keeps reconstructed itineraries so clients can retrieve them later
+type ItineraryStore struct {
+	repo repository.Repository
+	now  func() time.Time
+}
+
+// NewItineraryStore creates an itinerary store backed by repo
+func NewItineraryStore(repo repository.Repository) *ItineraryStore {
+	return &ItineraryStore{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// Save stores the itinerary reconstructed from request under a new ID
+func (s *ItineraryStore) Save(ctx context.Context, request *models.ItineraryRequest, itinerary []string) (*models.StoredItinerary, error) {
+	if err := request.ValidateDetails(); err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	stored := &models.StoredItinerary{
+		ID:         newID(),
+		Revision:   1,
+		Passenger:  request.Passenger,
+		TravelDate: request.TravelDate,
+		Tickets:    request.Tickets,
+		Itinerary:  itinerary,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.repo.Create(ctx, stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// Update applies patch to the itinerary stored under id as a new revision, splicing the
+// stored route rather than reconstructing it. When ifMatch is set, it is called with the
+// revision being patched and the update fails with ErrPreconditionFailed unless it agrees.
+func (s *ItineraryStore) Update(ctx context.Context, id string, patch *models.ItineraryPatch, ifMatch func(revision int) bool) (*models.StoredItinerary, error) {
+	if err := patch.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Retry when another update lands between reading and writing the itinerary
+	for {
+		current, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if ifMatch != nil && !ifMatch(current.Revision) {
+			return nil, ErrPreconditionFailed
+		}
+
+		itinerary, err := spliceItinerary(current.Itinerary, patch.Removed(), patch.Added())
+		if err != nil {
+			return nil, err
+		}
+
+		next := current
+		next.Revision++
+		next.Tickets = patchTickets(current.Tickets, patch)
+		next.Itinerary = itinerary
+		next.UpdatedAt = s.now().UTC()
+
+		err = s.repo.Update(ctx, next)
+		if errors.Is(err, repository.ErrConflict) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return next, nil
+	}
+}
+
+// Get returns the latest revision of the itinerary stored under id
+func (s *ItineraryStore) Get(ctx context.Context, id string) (*models.StoredItinerary, error) {
+	stored, err := s.repo.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrItineraryNotFound
+	}
+	return stored, err
+}
+
+// Revisions returns every revision of the itinerary stored under id, oldest first
+func (s *ItineraryStore) Revisions(ctx context.Context, id string) ([]*models.StoredItinerary, error) {
+	revisions, err := s.repo.Revisions(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrItineraryNotFound
+	}
+	return revisions, err
+}
+
+// Revision returns the given revision of the itinerary stored under id
+func (s *ItineraryStore) Revision(ctx context.Context, id string, revision int) (*models.StoredItinerary, error) {
+	revisions, err := s.Revisions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if revision < 1 || revision > len(revisions) {
+		return nil, ErrRevisionNotFound
+	}
+	return revisions[revision-1], nil
+}
+
+// Diff returns the changes between two revisions of the itinerary stored under id. A zero to
+// stands for the latest revision, and a zero from for the revision preceding to.
+func (s *ItineraryStore) Diff(ctx context.Context, id string, from, to int) (*models.ItineraryDiff, error) {
+	revisions, err := s.Revisions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if to == 0 {
+		to = len(revisions)
+	}
+	if from == 0 {
+		from = to - 1
+	}
+	if from < 1 || from > len(revisions) || to < 1 || to > len(revisions) {
+		return nil, ErrRevisionNotFound
+	}
+
+	diff := DiffItineraries(revisions[from-1].Itinerary, revisions[to-1].Itinerary)
+	diff.FromRevision, diff.ToRevision = from, to
+	return diff, nil
+}
+
+// List returns a page of the stored itineraries matching query, oldest first
+func (s *ItineraryStore) List(ctx context.Context, query *models.ItineraryQuery) (*models.ItineraryList, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	list, err := s.repo.List(ctx, query)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return nil, ErrInvalidCursor
+	}
+	return list, err
+}
+
+// patchTickets returns tickets without the tickets patch removes and with those it adds. The
+// patch has already been checked against the itinerary, so every removed ticket is present.
+func patchTickets(tickets []models.TicketPair, patch *models.ItineraryPatch) []models.TicketPair {
+	removed := make(map[[2]string]int)
+	for _, ticket := range patch.Removed() {
+		removed[[2]string{ticket[0], ticket[1]}]++
+	}
+
+	added := patch.Added()
+	patched := make([]models.TicketPair, 0, len(tickets)+len(added))
+	for _, ticket := range tickets {
+		key := [2]string{ticket[0], ticket[1]}
+		if removed[key] > 0 {
+			removed[key]--
+			continue
+		}
+		patched = append(patched, ticket)
+	}
+	return append(patched, added...)
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"flight-itinerary-api/models"
+	"flight-itinerary-api/repository"
+)
+
+func TestItineraryStore(t *testing.T) {
+	store := NewItineraryStore(repository.NewMemoryRepository())
+	ctx := context.Background()
+
+	saved, err := store.Save(ctx, &models.ItineraryRequest{Tickets: []models.TicketPair{{"SFO", "LAX"}}}, []string{"SFO", "LAX"})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if saved.ID == "" || saved.CreatedAt.IsZero() {
+		t.Errorf("Save() = %+v, want an ID and creation time", saved)
+	}
+
+	got, err := store.Get(ctx, saved.ID)
+	if err != nil || !reflect.DeepEqual(got, saved) {
+		t.Errorf("Get() = %+v, %v, want %+v", got, err, saved)
+	}
+
+	if _, err := store.Get(ctx, "unknown"); err != ErrItineraryNotFound {
+		t.Errorf("Get(unknown) error = %v, want ErrItineraryNotFound", err)
+	}
+}
+
+func TestItineraryStoreList(t *testing.T) {
+	store := NewItineraryStore(repository.NewMemoryRepository())
+	ctx := context.Background()
+
+	for _, passenger := range []string{"Ada", "Grace"} {
+		request := &models.ItineraryRequest{Tickets: []models.TicketPair{{"SFO", "LAX"}}, Passenger: passenger, TravelDate: "2026-10-20"}
+		if _, err := store.Save(ctx, request, []string{"SFO", "LAX"}); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	if _, err := store.Save(ctx, &models.ItineraryRequest{TravelDate: "tomorrow"}, nil); ErrorCode(err) != "invalid_travel_date" {
+		t.Errorf("Save(invalid travel date) error = %v", err)
+	}
+
+	list, err := store.List(ctx, &models.ItineraryQuery{Passenger: "grace", Limit: 10})
+	if err != nil || len(list.Itineraries) != 1 || list.Itineraries[0].Passenger != "Grace" {
+		t.Errorf("List() = %+v, %v", list, err)
+	}
+
+	if _, err := store.List(ctx, &models.ItineraryQuery{Cursor: "?", Limit: 10}); err != ErrInvalidCursor {
+		t.Errorf("List(invalid cursor) error = %v, want ErrInvalidCursor", err)
+	}
+	if _, err := store.List(ctx, &models.ItineraryQuery{}); ErrorCode(err) != "invalid_limit" {
+		t.Errorf("List(zero limit) error = %v", err)
+	}
+}
+
+func TestItineraryStoreUpdate(t *testing.T) {
+	store := NewItineraryStore(repository.NewMemoryRepository())
+	ctx := context.Background()
+
+	saved, err := store.Save(ctx, &models.ItineraryRequest{Tickets: []models.TicketPair{{"SFO", "LAX"}, {"LAX", "JFK"}}}, []string{"SFO", "LAX", "JFK"})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	patch := &models.ItineraryPatch{Replace: []models.TicketReplacement{{From: models.TicketPair{"LAX", "JFK"}, To: models.TicketPair{"LAX", "BOS"}}}}
+	stale := func(revision int) bool { return revision == 2 }
+	if _, err := store.Update(ctx, saved.ID, patch, stale); err != ErrPreconditionFailed {
+		t.Errorf("Update(stale) error = %v, want ErrPreconditionFailed", err)
+	}
+	if _, err := store.Update(ctx, "unknown", patch, nil); err != ErrItineraryNotFound {
+		t.Errorf("Update(unknown) error = %v, want ErrItineraryNotFound", err)
+	}
+
+	updated, err := store.Update(ctx, saved.ID, patch, func(revision int) bool { return revision == 1 })
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.Revision != 2 || !reflect.DeepEqual(updated.Itinerary, []string{"SFO", "LAX", "BOS"}) {
+		t.Errorf("Update() = %+v", updated)
+	}
+	if !reflect.DeepEqual(updated.Tickets, []models.TicketPair{{"SFO", "LAX"}, {"LAX", "BOS"}}) {
+		t.Errorf("Update() tickets = %v", updated.Tickets)
+	}
+
+	// The replaced ticket is no longer part of the itinerary
+	if _, err := store.Update(ctx, saved.ID, patch, nil); err != ErrTicketNotFound {
+		t.Errorf("Update(replaced ticket) error = %v, want ErrTicketNotFound", err)
+	}
+
+	first, err := store.Revision(ctx, saved.ID, 1)
+	if err != nil || !reflect.DeepEqual(first, saved) {
+		t.Errorf("Revision(1) = %+v, %v, want %+v", first, err, saved)
+	}
+	if _, err := store.Revision(ctx, saved.ID, 3); err != ErrRevisionNotFound {
+		t.Errorf("Revision(3) error = %v, want ErrRevisionNotFound", err)
+	}
+	if revisions, err := store.Revisions(ctx, saved.ID); err != nil || len(revisions) != 2 {
+		t.Errorf("Revisions() = %d revisions, %v, want 2", len(revisions), err)
+	}
+}
+
+func TestItineraryStoreDiff(t *testing.T) {
+	store := NewItineraryStore(repository.NewMemoryRepository())
+	ctx := context.Background()
+
+	saved, err := store.Save(ctx, &models.ItineraryRequest{Tickets: []models.TicketPair{{"SFO", "LAX"}, {"LAX", "JFK"}}}, []string{"SFO", "LAX", "JFK"})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := store.Diff(ctx, saved.ID, 0, 0); err != ErrRevisionNotFound {
+		t.Errorf("Diff(single revision) error = %v, want ErrRevisionNotFound", err)
+	}
+
+	for _, patch := range []*models.ItineraryPatch{
+		{Add: []models.TicketPair{{"JFK", "BOS"}}},
+		{Replace: []models.TicketReplacement{{From: models.TicketPair{"SFO", "LAX"}, To: models.TicketPair{"SFO", "DEN"}}, {From: models.TicketPair{"LAX", "JFK"}, To: models.TicketPair{"DEN", "JFK"}}}},
+	} {
+		if _, err := store.Update(ctx, saved.ID, patch, nil); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+
+	// The latest revision is compared with the one before it by default
+	diff, err := store.Diff(ctx, saved.ID, 0, 0)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if diff.FromRevision != 2 || diff.ToRevision != 3 || len(diff.Rerouted) != 1 || len(diff.Added) != 0 {
+		t.Errorf("Diff() = %+v", diff)
+	}
+
+	diff, err = store.Diff(ctx, saved.ID, 1, 3)
+	if err != nil {
+		t.Fatalf("Diff(1, 3) error = %v", err)
+	}
+	if !reflect.DeepEqual(diff.Added, []models.TicketPair{{"JFK", "BOS"}}) || !reflect.DeepEqual(diff.AddedAirports, []string{"DEN", "BOS"}) {
+		t.Errorf("Diff(1, 3) = %+v", diff)
+	}
+
+	if _, err := store.Diff(ctx, saved.ID, 1, 4); err != ErrRevisionNotFound {
+		t.Errorf("Diff(1, 4) error = %v, want ErrRevisionNotFound", err)
+	}
+	if _, err := store.Diff(ctx, "unknown", 0, 0); err != ErrItineraryNotFound {
+		t.Errorf("Diff(unknown) error = %v, want ErrItineraryNotFound", err)
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"flight-itinerary-api/models"
+)
+
+// StreamRequest is a single ticket set read from a stream, tagged with its input position.
+// Err is set when the ticket set could not be decoded; it is reported without being processed.
+type StreamRequest struct {
+	Index   int
+	Request models.ItineraryRequest
+	Err     error
+}
+
+// StreamResult holds the outcome of a single ticket set of a stream
+type StreamResult struct {
+	Index     int
+	Itinerary []string
+	Err       error
+}
+
+// ReconstructStream processes ticket sets as they arrive on requests and calls emit for each
+// result as soon as it is computed, so results may be emitted out of input order.
+//
+// At most streamInFlight ticket sets are held at once: a slot is only released after emit
+// returns, so a slow consumer stops the intake of new ticket sets instead of growing memory.
+// It returns once requests is closed and every result has been emitted, or with the first
+// error returned by emit or the context.
+func (s *ItineraryService) ReconstructStream(ctx context.Context, requests <-chan StreamRequest, emit func(StreamResult) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	slots := make(chan struct{}, s.streamInFlight)
+	// Buffered to the window size, so workers never block on delivering a result
+	results := make(chan StreamResult, s.streamInFlight)
+
+	var wg sync.WaitGroup
+	dispatchDone := make(chan struct{})
+
+	// Dispatch ticket sets to the pool while slots are available
+	go func() {
+		defer close(dispatchDone)
+
+		for {
+			var (
+				item StreamRequest
+				ok   bool
+			)
+			select {
+			case item, ok = <-requests:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			if item.Err == nil {
+				item.Err = item.Request.Validate()
+			}
+			if item.Err != nil {
+				results <- StreamResult{Index: item.Index, Err: item.Err}
+				continue
+			}
+
+			// Streams wait for a worker rather than being shed; the window already bounds them
+			pool := s.poolFor(len(item.Request.Tickets))
+			if err := pool.admission.acquireWait(ctx); err != nil {
+				results <- StreamResult{Index: item.Index, Err: err}
+				continue
+			}
+
+			report := progressReporter(ctx, item.Index)
+			wg.Add(1)
+			submitErr := pool.executor.Submit(func() {
+				defer wg.Done()
+				release := releaseOnce(pool.admission)
+				defer release()
+
+				// Skip work queued before the stream was abandoned
+				if err := ctx.Err(); err != nil {
+					release()
+					results <- StreamResult{Index: item.Index, Err: err}
+					return
+				}
+
+				// Each ticket set gets its own processing deadline once a worker picks it up
+				itemCtx, cancelItem := s.withDeadline(ctx)
+				defer cancelItem()
+
+				itinerary, err := deadlineError(s.runTask(func() ([]string, error) {
+					return s.processItinerary(itemCtx, &item.Request, report)
+				}))
+				release()
+				results <- StreamResult{Index: item.Index, Itinerary: itinerary, Err: err}
+			})
+
+			if submitErr != nil {
+				pool.admission.release()
+				wg.Done()
+				results <- StreamResult{Index: item.Index, Err: submitErr}
+			}
+		}
+	}()
+
+	// Close results once dispatching has stopped and every submitted task has reported
+	go func() {
+		<-dispatchDone
+		wg.Wait()
+		close(results)
+	}()
+
+	var emitErr error
+	for result := range results {
+		if emitErr == nil {
+			if emitErr = emit(result); emitErr != nil {
+				// Stop intake; remaining results are drained but discarded
+				cancel()
+			}
+		}
+		<-slots
+	}
+
+	if emitErr != nil {
+		return emitErr
+	}
+	return ctx.Err()
+}
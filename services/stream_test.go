@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/models"
+)
+
+func TestReconstructStream(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 2,
+		},
+		Stream: config.StreamConfig{
+			MaxInFlight: 2,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	items := []StreamRequest{
+		{Index: 0, Request: models.ItineraryRequest{Tickets: []models.TicketPair{{"LAX", "JFK"}, {"SFO", "LAX"}}}},
+		{Index: 1, Err: models.ErrInvalidFormat},
+		{Index: 2, Request: models.ItineraryRequest{Tickets: []models.TicketPair{{"SFO", "LAX"}, {"JFK", "MCO"}}}},
+		{Index: 3, Request: models.ItineraryRequest{Tickets: []models.TicketPair{{"SFO", "JFK"}}}},
+		{Index: 4, Request: models.ItineraryRequest{}},
+	}
+
+	requests := make(chan StreamRequest)
+	go func() {
+		defer close(requests)
+		for _, item := range items {
+			requests <- item
+		}
+	}()
+
+	var results []StreamResult
+	err := service.ReconstructStream(context.Background(), requests, func(result StreamResult) error {
+		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReconstructStream() error = %v", err)
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+	if len(results) != len(items) {
+		t.Fatalf("ReconstructStream() emitted %d results, want %d", len(results), len(items))
+	}
+
+	wantItineraries := [][]string{{"SFO", "LAX", "JFK"}, nil, nil, {"SFO", "JFK"}, nil}
+	wantErrs := []bool{false, true, true, false, true}
+	for i, result := range results {
+		if (result.Err != nil) != wantErrs[i] {
+			t.Errorf("item %d: error = %v, wantErr %v", i, result.Err, wantErrs[i])
+		}
+		if !reflect.DeepEqual(result.Itinerary, wantItineraries[i]) {
+			t.Errorf("item %d: itinerary = %v, want %v", i, result.Itinerary, wantItineraries[i])
+		}
+	}
+}
+
+func TestReconstructStreamStopsOnEmitError(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{
+			WorkerCount: 2,
+		},
+		Stream: config.StreamConfig{
+			MaxInFlight: 1,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service := NewItineraryService(ctx, cfg)
+
+	// An endless producer must be stopped once the consumer fails
+	requests := make(chan StreamRequest)
+	go func() {
+		defer close(requests)
+		for i := 0; ; i++ {
+			item := StreamRequest{Index: i, Request: models.ItineraryRequest{Tickets: []models.TicketPair{{"SFO", "JFK"}}}}
+			select {
+			case requests <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	errClientGone := errors.New("client gone")
+	emitted := 0
+	err := service.ReconstructStream(context.Background(), requests, func(StreamResult) error {
+		emitted++
+		if emitted == 3 {
+			return errClientGone
+		}
+		return nil
+	})
+
+	if !errors.Is(err, errClientGone) {
+		t.Errorf("ReconstructStream() error = %v, want %v", err, errClientGone)
+	}
+	if emitted != 3 {
+		t.Errorf("ReconstructStream() emitted %d results after failure, want 3", emitted)
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// ErrInternal is returned when a reconstruction fails because of a bug rather than its input
+var ErrInternal = NewError(KindInternal, codeInternal, "internal error")
+
+// PanicError reports a panic recovered from a reconstruction task. It matches ErrInternal,
+// and its message never includes the panic value, which may carry request data.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+// Error returns a message safe to show to clients
+func (e *PanicError) Error() string {
+	return "internal error: reconstruction failed unexpectedly"
+}
+
+// Unwrap makes PanicError match ErrInternal
+func (e *PanicError) Unwrap() error {
+	return ErrInternal
+}
+
+// taskResult carries the outcome of a reconstruction task
+type taskResult struct {
+	itinerary []string
+	err       error
+}
+
+// submit runs fn on the worker pool suited to its cost in tickets and returns a channel that
+// receives its single result. The channel is buffered, so a task whose caller stopped waiting
+// completes without blocking and shares no variables with it. A task still queued when ctx is
+// cancelled is skipped. Callers that cannot be admitted to the pool get ErrOverloaded. The
+// outcome also lands call, if there is one, as soon as it is known.
+func (s *ItineraryService) submit(ctx context.Context, cost int, call *sharedCall, fn func() ([]string, error)) (<-chan taskResult, error) {
+	pool := s.poolFor(cost)
+	if err := pool.admission.acquire(ctx); err != nil {
+		call.land(nil, err)
+		return nil, err
+	}
+
+	resultCh := make(chan taskResult, 1)
+
+	err := pool.executor.Submit(func() {
+		release := releaseOnce(pool.admission)
+		defer release()
+
+		if err := ctx.Err(); err != nil {
+			release()
+			call.land(nil, err)
+			resultCh <- taskResult{err: err}
+			return
+		}
+
+		itinerary, err := s.runTask(fn)
+		release()
+		call.land(itinerary, err)
+		resultCh <- taskResult{itinerary: itinerary, err: err}
+	})
+	if err != nil {
+		pool.admission.release()
+		err = fmt.Errorf("%w: %v", ErrOverloaded, err)
+		call.land(nil, err)
+		return nil, err
+	}
+
+	return resultCh, nil
+}
+
+// releaseOnce returns a function returning the worker of a task to admission on its first call.
+// Tasks call it before reporting their result, so that a caller sending its next request as
+// soon as it has the result is never turned away by its own finished task, and defer it so
+// that the worker is returned even if the task panics.
+func releaseOnce(admission *admission) func() {
+	var once sync.Once
+	return func() {
+		once.Do(admission.release)
+	}
+}
+
+// await waits for a submitted task's result or the cancellation of ctx
+func await(ctx context.Context, resultCh <-chan taskResult) ([]string, error) {
+	select {
+	case result := <-resultCh:
+		return result.itinerary, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// runTask runs fn, converting a panic into a PanicError so a failing task can never be
+// mistaken for an empty success or take the worker down with it
+func (s *ItineraryService) runTask(fn func() ([]string, error)) (itinerary []string, err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			panicErr := &PanicError{Value: value, Stack: debug.Stack()}
+			s.panics.Inc()
+			s.logger.Error("Reconstruction task panicked",
+				zap.Any("panic", value),
+				zap.ByteString("stack", panicErr.Stack),
+			)
+			itinerary, err = nil, panicErr
+		}
+	}()
+
+	return fn()
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"flight-itinerary-api/config"
+)
+
+// Headers sent with every callback delivery
+const (
+	HeaderWebhookID        = "X-Webhook-Id"
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp"
+	HeaderWebhookSignature = "X-Webhook-Signature"
+)
+
+// DeliveryStatus describes the state of a job completion callback
+type DeliveryStatus string
+
+// Callback delivery states
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// DeliveryError categorises why a callback delivery attempt failed. Only the category is kept,
+// so that delivery reports never disclose what the server learnt about the receiver's network.
+type DeliveryError string
+
+// Callback delivery failure categories
+const (
+	DeliveryErrTimeout          DeliveryError = "timeout"
+	DeliveryErrConnection       DeliveryError = "connection_failed"
+	DeliveryErrForbiddenAddress DeliveryError = "forbidden_address"
+	DeliveryErrInvalidRequest   DeliveryError = "invalid_request"
+	DeliveryErrCancelled        DeliveryError = "cancelled"
+	DeliveryErrStatus3xx        DeliveryError = "status_3xx"
+	DeliveryErrStatus4xx        DeliveryError = "status_4xx"
+	DeliveryErrStatus5xx        DeliveryError = "status_5xx"
+)
+
+// DeliveryAttempt records the outcome of a single callback delivery attempt
+type DeliveryAttempt struct {
+	Attempt    int
+	At         time.Time
+	StatusCode int
+	Error      DeliveryError // Empty when the receiver acknowledged the delivery
+}
+
+// Callback delivery errors
+var (
+	errPermanentDelivery = errors.New("callback rejected by receiver")  // Retrying cannot fix the response
+	errForbiddenAddress  = errors.New("callback address is not public") // The receiver is on a private network
+)
+
+// WebhookNotifier delivers signed job completion callbacks with exponential backoff
+type WebhookNotifier struct {
+	client         *http.Client
+	allowPrivate   bool
+	secret         []byte
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// NewWebhookNotifier creates a new instance of WebhookNotifier. Unless cfg allows private
+// networks, callbacks are only delivered to public addresses: the check runs on the address
+// actually dialled, so a host name resolving to a private address, even after it was
+// accepted, cannot reach the internal network. Redirects are never followed.
+func NewWebhookNotifier(cfg *config.WebhookConfig) *WebhookNotifier {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = checkDialedAddress
+	}
+
+	return &WebhookNotifier{
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// No proxy, which would be dialled instead of the receiver and defeat the address check
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: cfg.Timeout,
+				MaxIdleConns:        10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		allowPrivate:   cfg.AllowPrivateNetworks,
+		secret:         []byte(cfg.Secret),
+		maxAttempts:    max(cfg.MaxAttempts, 1),
+		initialBackoff: cfg.InitialBackoff,
+		maxBackoff:     cfg.MaxBackoff,
+	}
+}
+
+// Enabled reports whether callbacks can be signed and therefore accepted
+func (n *WebhookNotifier) Enabled() bool {
+	return len(n.secret) > 0
+}
+
+// Permits reports whether callbacks may be sent to rawURL. Only URLs naming a private address
+// or localhost outright are refused here; host names are checked when they are dialled.
+func (n *WebhookNotifier) Permits(rawURL string) bool {
+	if n.allowPrivate {
+		return true
+	}
+	callback, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(callback.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return publicIP(ip)
+	}
+	return true
+}
+
+// Deliver POSTs payload to url until the receiver acknowledges it with a 2xx status, the
+// attempts are exhausted or ctx is cancelled. Every attempt is passed to record.
+func (n *WebhookNotifier) Deliver(ctx context.Context, url, id string, payload []byte, record func(DeliveryAttempt)) DeliveryStatus {
+	backoff := n.initialBackoff
+
+	for attempt := 1; attempt <= n.maxAttempts; attempt++ {
+		statusCode, err := n.post(ctx, url, id, payload)
+		record(DeliveryAttempt{
+			Attempt:    attempt,
+			At:         time.Now(),
+			StatusCode: statusCode,
+			Error:      deliveryError(statusCode, err),
+		})
+
+		if err == nil {
+			return DeliveryDelivered
+		}
+		if errors.Is(err, errPermanentDelivery) || errors.Is(err, errForbiddenAddress) || attempt == n.maxAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return DeliveryFailed
+		}
+
+		backoff *= 2
+		if n.maxBackoff > 0 && backoff > n.maxBackoff {
+			backoff = n.maxBackoff
+		}
+	}
+
+	return DeliveryFailed
+}
+
+// post performs a single signed delivery and classifies the receiver's response
+func (n *WebhookNotifier) post(ctx context.Context, url, id string, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errPermanentDelivery, err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookID, id)
+	req.Header.Set(HeaderWebhookTimestamp, timestamp)
+	req.Header.Set(HeaderWebhookSignature, SignWebhookPayload(n.secret, timestamp, payload))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return resp.StatusCode, nil
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusRequestTimeout:
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	default:
+		return resp.StatusCode, fmt.Errorf("%w: status %d", errPermanentDelivery, resp.StatusCode)
+	}
+}
+
+// deliveryError returns the category of the outcome of a delivery attempt
+func deliveryError(statusCode int, err error) DeliveryError {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return ""
+	case statusCode >= 500:
+		return DeliveryErrStatus5xx
+	case statusCode >= 400:
+		return DeliveryErrStatus4xx
+	case statusCode >= 300:
+		return DeliveryErrStatus3xx
+	case errors.Is(err, errForbiddenAddress):
+		return DeliveryErrForbiddenAddress
+	case errors.Is(err, errPermanentDelivery):
+		return DeliveryErrInvalidRequest
+	case errors.Is(err, context.Canceled):
+		return DeliveryErrCancelled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return DeliveryErrTimeout
+	default:
+		return DeliveryErrConnection
+	}
+}
+
+// checkDialedAddress refuses connections to addresses that are not public. It runs once the
+// host name has been resolved, for every address dialled.
+func checkDialedAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return errForbiddenAddress
+	}
+	return nil
+}
+
+// publicIP reports whether ip is reachable on the public internet rather than being a
+// loopback, private, link-local, multicast or unspecified address
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// SignWebhookPayload computes the signature header value for a callback: the hex encoded
+// HMAC-SHA256 of the timestamp and payload joined by a dot
+func SignWebhookPayload(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
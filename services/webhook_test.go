@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"flight-itinerary-api/config"
+	"flight-itinerary-api/models"
+)
+
+func TestWebhookNotifierDeliver(t *testing.T) {
+	secret := "test-secret"
+
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantStatus   DeliveryStatus
+		wantAttempts int
+	}{
+		{
+			name:         "delivered after a server error",
+			statuses:     []int{http.StatusInternalServerError, http.StatusOK},
+			wantStatus:   DeliveryDelivered,
+			wantAttempts: 2,
+		},
+		{
+			name:         "client errors are not retried",
+			statuses:     []int{http.StatusBadRequest},
+			wantStatus:   DeliveryFailed,
+			wantAttempts: 1,
+		},
+		{
+			name:         "attempts are exhausted",
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+			wantStatus:   DeliveryFailed,
+			wantAttempts: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				want := SignWebhookPayload([]byte(secret), r.Header.Get(HeaderWebhookTimestamp), body)
+				if got := r.Header.Get(HeaderWebhookSignature); got != want {
+					t.Errorf("signature = %q, want %q", got, want)
+				}
+				if got := r.Header.Get(HeaderWebhookID); got != "job-1" {
+					t.Errorf("delivery ID = %q, want job-1", got)
+				}
+
+				call := atomic.AddInt32(&calls, 1)
+				w.WriteHeader(tt.statuses[call-1])
+			}))
+			defer receiver.Close()
+
+			notifier := NewWebhookNotifier(&config.WebhookConfig{
+				Secret:               secret,
+				MaxAttempts:          3,
+				InitialBackoff:       time.Millisecond,
+				MaxBackoff:           2 * time.Millisecond,
+				Timeout:              time.Second,
+				AllowPrivateNetworks: true, // The receiver listens on loopback
+			})
+
+			var attempts []DeliveryAttempt
+			status := notifier.Deliver(context.Background(), receiver.URL, "job-1", []byte(`{"id":"job-1"}`), func(attempt DeliveryAttempt) {
+				attempts = append(attempts, attempt)
+			})
+
+			if status != tt.wantStatus {
+				t.Errorf("Deliver() status = %v, want %v", status, tt.wantStatus)
+			}
+			if len(attempts) != tt.wantAttempts {
+				t.Fatalf("Deliver() recorded %d attempts, want %d", len(attempts), tt.wantAttempts)
+			}
+			for i, attempt := range attempts {
+				if attempt.Attempt != i+1 || attempt.StatusCode != tt.statuses[i] {
+					t.Errorf("attempt %d = %+v", i, attempt)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookNotifierPrivateNetworks(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer receiver.Close()
+
+	cfg := config.WebhookConfig{
+		Secret:         "test-secret",
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		Timeout:        time.Second,
+	}
+	deliver := func(notifier *WebhookNotifier, url string) (DeliveryStatus, []DeliveryAttempt) {
+		var attempts []DeliveryAttempt
+		status := notifier.Deliver(context.Background(), url, "job-1", []byte(`{}`), func(attempt DeliveryAttempt) {
+			attempts = append(attempts, attempt)
+		})
+		return status, attempts
+	}
+
+	// A receiver on a private network is never dialled, nor retried
+	status, attempts := deliver(NewWebhookNotifier(&cfg), receiver.URL)
+	if status != DeliveryFailed || len(attempts) != 1 || attempts[0].Error != DeliveryErrForbiddenAddress {
+		t.Errorf("Deliver(loopback) = %v, %+v", status, attempts)
+	}
+	if calls != 0 {
+		t.Errorf("loopback receiver called %d times", calls)
+	}
+
+	// Once allowed, redirects are reported rather than followed
+	cfg.AllowPrivateNetworks = true
+	allowed := NewWebhookNotifier(&cfg)
+	status, attempts = deliver(allowed, receiver.URL)
+	if status != DeliveryFailed || len(attempts) != 1 || attempts[0].Error != DeliveryErrStatus3xx || attempts[0].StatusCode != http.StatusFound {
+		t.Errorf("Deliver(redirect) = %v, %+v", status, attempts)
+	}
+
+	// Transport failures are reported by category only
+	receiver.Close()
+	_, attempts = deliver(allowed, receiver.URL)
+	for _, attempt := range attempts {
+		if attempt.Error != DeliveryErrConnection {
+			t.Errorf("Deliver(closed receiver) attempt = %+v, want %s", attempt, DeliveryErrConnection)
+		}
+	}
+}
+
+func TestWebhookNotifierPermits(t *testing.T) {
+	notifier := NewWebhookNotifier(&config.WebhookConfig{Secret: "test-secret"})
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://hooks.example.com/itinerary", true},
+		{"http://93.184.216.34/hook", true},
+		{"http://localhost:8080/hook", false},
+		{"http://api.localhost/hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://10.0.0.5:6379/", false},
+		{"http://192.168.1.1/", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://0.0.0.0/", false},
+		{"http://[::1]/hook", false},
+		{"http://[fd00::1]/hook", false},
+	}
+
+	for _, tt := range tests {
+		if got := notifier.Permits(tt.url); got != tt.want {
+			t.Errorf("Permits(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	allowed := NewWebhookNotifier(&config.WebhookConfig{Secret: "test-secret", AllowPrivateNetworks: true})
+	if !allowed.Permits("http://127.0.0.1/hook") {
+		t.Error("Permits(loopback) = false with private networks allowed")
+	}
+}
+
+func TestJobManagerDeliversCallback(t *testing.T) {
+	received := make(chan models.JobResponse, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response models.JobResponse
+		_ = json.NewDecoder(r.Body).Decode(&response)
+		received <- response
+	}))
+	defer receiver.Close()
+
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{WorkerCount: 2},
+		Jobs:       config.JobsConfig{QueueSize: 1, Workers: 1, MaxItems: 10},
+		Webhook: config.WebhookConfig{
+			Secret:               "test-secret",
+			MaxAttempts:          1,
+			InitialBackoff:       time.Millisecond,
+			Timeout:              time.Second,
+			AllowPrivateNetworks: true,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewJobManager(ctx, cfg, NewItineraryService(ctx, cfg))
+
+	job, err := m.Submit(&models.JobRequest{
+		Tickets:     []models.TicketPair{{"SFO", "JFK"}},
+		CallbackURL: receiver.URL,
+	})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	select {
+	case response := <-received:
+		if response.ID != job.ID || response.Status != string(JobCompleted) {
+			t.Errorf("callback payload = %+v", response)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("callback was not delivered")
+	}
+
+	// The delivery outcome is recorded once the receiver has responded
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, err = m.Deliveries(job.ID)
+		if err != nil {
+			t.Fatalf("Deliveries() error = %v", err)
+		}
+		if job.DeliveryStatus == DeliveryDelivered || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if job.DeliveryStatus != DeliveryDelivered || len(job.Deliveries) != 1 {
+		t.Errorf("delivery status = %v with %d attempts", job.DeliveryStatus, len(job.Deliveries))
+	}
+}
+
+func TestJobManagerRejectsCallbacksWithoutSecret(t *testing.T) {
+	m := newTestJobManager(t, config.JobsConfig{QueueSize: 1, MaxItems: 10})
+
+	_, err := m.Submit(&models.JobRequest{
+		Tickets:     []models.TicketPair{{"SFO", "JFK"}},
+		CallbackURL: "http://localhost/callback",
+	})
+	if err != ErrCallbacksDisabled {
+		t.Errorf("Submit() error = %v, want %v", err, ErrCallbacksDisabled)
+	}
+}
+
+func TestJobManagerRejectsPrivateCallbacks(t *testing.T) {
+	cfg := &config.AppConfig{
+		WorkerPool: config.WorkerPoolConfig{WorkerCount: 1},
+		Jobs:       config.JobsConfig{QueueSize: 1, MaxItems: 10},
+		Webhook:    config.WebhookConfig{Secret: "test-secret", MaxAttempts: 1, Timeout: time.Second},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewJobManager(ctx, cfg, NewItineraryService(ctx, cfg))
+
+	_, err := m.Submit(&models.JobRequest{
+		Tickets:     []models.TicketPair{{"SFO", "JFK"}},
+		CallbackURL: "http://169.254.169.254/latest/meta-data/",
+	})
+	if err != ErrCallbackForbidden {
+		t.Errorf("Submit() error = %v, want %v", err, ErrCallbackForbidden)
+	}
+}